@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yaeshimo/go-utils/gotcha"
+)
+
+func TestBaseline(t *testing.T) {
+	root := filepath.Join(TestRoot, "baseline")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "main.go")
+	write := func(contents string) {
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	baseline := filepath.Join(root, "baseline.json")
+	newopt := func() *option {
+		return &option{root: root, baseline: baseline, noConfig: true}
+	}
+
+	// record
+	write("// TODO: old\n// TODO: old\n")
+	buf, errbuf := bytes.NewBufferString(""), bytes.NewBufferString("")
+	if exit := run(buf, errbuf, newopt()); exit != ValidExit {
+		t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+	}
+	base, err := gotcha.ReadBaseline(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(base.Entries) != 1 || *base.Entries[0] != (gotcha.BaselineEntry{Path: "main.go", Tag: "TODO: ", Text: "old", Count: 2}) {
+		t.Fatalf("unexpected baseline: %#v", base.Entries)
+	}
+
+	t.Run("baseline is not gathered", func(t *testing.T) {
+		opt := &option{root: root, noConfig: true, format: "json"}
+		buf.Reset()
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+		if strings.Contains(buf.String(), "baseline.json") {
+			t.Errorf("unexpected matches of baseline: %s", buf)
+		}
+	})
+
+	t.Run("shifted lines and spaces", func(t *testing.T) {
+		write("package main\n\n//   TODO:   old\n\t// TODO: old\n")
+		opt := newopt()
+		opt.check = true
+		buf.Reset()
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+	})
+
+	t.Run("new match", func(t *testing.T) {
+		write("// TODO: old\n// TODO: new\n// TODO: old\n// TODO: old\n")
+		opt := newopt()
+		opt.check = true
+		buf.Reset()
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ErrCheck {
+			t.Fatalf("expected exit=%d but exit=%d errbuf=%s", ErrCheck, exit, errbuf)
+		}
+		exp := "new: " + path + ":2: // TODO: new\n" + "new: " + path + ":4: // TODO: old\n"
+		if exp != errbuf.String() {
+			t.Errorf("exp=%#v out=%#v", exp, errbuf.String())
+		}
+	})
+
+	t.Run("max total", func(t *testing.T) {
+		if err := os.Remove(baseline); err != nil {
+			t.Fatal(err)
+		}
+		write("// TODO: one\n// TODO: two\n")
+		opt := &option{root: root, noConfig: true, maxTotal: 1}
+		buf.Reset()
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ErrCheck {
+			t.Fatalf("expected exit=%d but exit=%d errbuf=%s", ErrCheck, exit, errbuf)
+		}
+		opt.maxTotal = 2
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+	})
+
+	t.Run("overdue", func(t *testing.T) {
+		write("// TODO(alice, 2001-01-01): old\n// TODO(alice, 2999-01-01): new\n")
+		opt := &option{root: root, noConfig: true, words: []string{"TODO"}}
+		buf.Reset()
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+		opt.overdue = true
+		if exit := run(buf, errbuf, opt); exit != ErrCheck {
+			t.Fatalf("expected exit=%d but exit=%d errbuf=%s", ErrCheck, exit, errbuf)
+		}
+		if exp := "overdue: " + path + ":1: // TODO(alice, 2001-01-01): old\n"; exp != errbuf.String() {
+			t.Errorf("exp=%#v out=%#v", exp, errbuf.String())
+		}
+
+		// annotated after the stem of the default word
+		opt.words = nil
+		errbuf.Reset()
+		if exit := run(buf, errbuf, opt); exit != ErrCheck {
+			t.Fatalf("expected exit=%d but exit=%d errbuf=%s", ErrCheck, exit, errbuf)
+		}
+	})
+
+	t.Run("check without baseline", func(t *testing.T) {
+		opt := &option{root: root, noConfig: true, check: true}
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Fatalf("expected exit=%d but exit=%d", ErrInitialize, exit)
+		}
+		if !strings.Contains(errbuf.String(), "-baseline") {
+			t.Errorf("unexpected message: %s", errbuf)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yaeshimo/go-utils/gotcha"
+)
+
+func TestApplyConfig(t *testing.T) {
+	max, add := 100, uint(2)
+	conf := &gotcha.Config{
+		Words:      []string{"FIXME: ", "BUG: "},
+		IgnoreDirs: []string{"vendor", "testdata"},
+		Max:        &max,
+		Add:        &add,
+	}
+	sep := string(filepath.ListSeparator)
+	opt := &option{
+		ignoreDirs: ".git",
+		maxRune:    256,
+		set:        map[string]bool{"max": true},
+	}
+	opt.applyConfig(conf)
+	if !reflect.DeepEqual(conf.Words, opt.words) {
+		t.Errorf("exp=%#v out=%#v", conf.Words, opt.words)
+	}
+	if exp := ".git" + sep + "vendor" + sep + "testdata"; exp != opt.ignoreDirs {
+		t.Errorf("exp=%#v out=%#v", exp, opt.ignoreDirs)
+	}
+	if opt.maxRune != 256 {
+		t.Errorf("expected keep explicitly specified flag but %d", opt.maxRune)
+	}
+	if opt.add != 2 {
+		t.Errorf("exp=%d out=%d", 2, opt.add)
+	}
+}
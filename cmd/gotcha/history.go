@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/yaeshimo/go-utils/gotcha"
+)
+
+// runHistory is "gotcha history", write counts of each commits
+func runHistory(w, errw io.Writer, args []string) int {
+	var (
+		fs           = flag.NewFlagSet(Name+" history", flag.ContinueOnError)
+		words        []string
+		rev          = fs.String("range", "HEAD", "specify revision range of git rev-list e.g. v1.0..HEAD")
+		format       = fs.String("format", "csv", "specify output format "+strings.Join(gotcha.HistoryFormats, "|"))
+		types        = fs.String("types", "", "specify filetypes. separator is '"+string(filepath.ListSeparator)+"'")
+		commentsOnly = fs.Bool("comments-only", false, "drop matches of outside comments, language is selected by file extension")
+		cachePath    = fs.String("cache-file", "", "specify cache of counts by tree hash, default is in git directory")
+		noCache      = fs.Bool("no-cache", false, "do not read and write cache")
+		noConfig     = fs.Bool("no-config", false, "do not read "+gotcha.ConfigName+" of dir and parents")
+		verbose      = fs.Bool("verbose", false, "verbose output")
+	)
+	fs.Var(newWordsValue(&words, []string{"TODO: "}), "word", "specify search word. can be repeated for multiple tags")
+	fs.SetOutput(errw)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ValidExit
+		}
+		return ErrInitialize
+	}
+	dir := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fmt.Fprintln(errw, "unknown arguments: ", fs.Args())
+		return ErrInitialize
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if !contains(gotcha.HistoryFormats, *format) {
+		fmt.Fprintln(errw, "unknown format: ", *format)
+		return ErrInitialize
+	}
+
+	g := gotcha.NewGotcha()
+	g.Words = words
+	g.CommentsOnly = *commentsOnly
+	if *verbose {
+		g.Log.SetOutput(errw)
+	} else {
+		g.Log.SetOutput(ioutil.Discard)
+	}
+	if !*noConfig {
+		conf, err := gotcha.LoadConfig(dir)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			return ErrInitialize
+		}
+		if conf != nil {
+			if len(conf.Words) != 0 && !set["word"] {
+				g.Words = conf.Words
+			}
+			if len(conf.Types) != 0 && !set["types"] {
+				*types = strings.Join(conf.Types, string(filepath.ListSeparator))
+			}
+			for _, list := range []struct {
+				m   map[string]bool
+				add []string
+			}{
+				{g.IgnoreDirsMap, conf.IgnoreDirs},
+				{g.IgnoreBasesMap, conf.IgnoreBases},
+				{g.IgnoreTypesMap, conf.IgnoreTypes},
+			} {
+				for _, s := range list.add {
+					list.m[s] = true
+				}
+			}
+			if conf.Encoding != "" {
+				g.Encoding = conf.Encoding
+			}
+			for ext, enc := range conf.Encodings {
+				g.EncodingsMap[ext] = enc
+			}
+		}
+	}
+	for _, t := range filepath.SplitList(*types) {
+		g.TypesMap[t] = true
+	}
+
+	var cache *gotcha.HistoryCache
+	if !*noCache {
+		if *cachePath == "" {
+			path, err := gotcha.HistoryCachePath(dir)
+			if err != nil {
+				fmt.Fprintln(errw, err)
+				return ErrInitialize
+			}
+			*cachePath = path
+		}
+		c, err := gotcha.LoadHistoryCache(*cachePath)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			return ErrInitialize
+		}
+		cache = c
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	points, err := g.History(ctx, dir, *rev, cache)
+	if err != nil {
+		fmt.Fprintln(errw, err)
+		return ErrRun
+	}
+	if cache != nil {
+		if err := cache.Save(*cachePath); err != nil {
+			fmt.Fprintln(errw, err)
+			return ErrRun
+		}
+	}
+	if err := g.WriteHistory(w, *format, points); err != nil {
+		fmt.Fprintln(errw, err)
+		return ErrRun
+	}
+	return ValidExit
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/yaeshimo/go-utils/gotcha"
+)
+
+func TestRunHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root := filepath.Join(TestRoot, "history")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	gitRun(t, root, "", "init", "-q")
+	for _, s := range []string{"TODO: a\n", "TODO: a\nTODO: b\n"} {
+		if err := ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+		gitRun(t, root, "", "add", "-A")
+		gitRun(t, root, "", "commit", "-q", "-m", s)
+	}
+
+	buf, errbuf := new(bytes.Buffer), new(bytes.Buffer)
+	if exit := runHistory(buf, errbuf, []string{"-format", "json", root}); exit != ValidExit {
+		t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+	}
+	var points []*gotcha.Point
+	if err := json.Unmarshal(buf.Bytes(), &points); err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].Lines != 1 || points[1].Lines != 2 {
+		t.Errorf("unexpected points: %s", buf)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git", gotcha.Name, "history.json")); err != nil {
+		t.Errorf("expected cache: %v", err)
+	}
+
+	if exit := runHistory(buf, errbuf, []string{"-format", "unknown", root}); exit != ErrInitialize {
+		t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+	}
+}
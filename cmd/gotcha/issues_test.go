@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yaeshimo/go-utils/gotcha"
+)
+
+func TestExportIssues(t *testing.T) {
+	root := filepath.Join(TestRoot, "export_issues")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "main.go")
+	tracker := filepath.Join(root, "tracker.json")
+	export := func(t *testing.T, contents string) []*gotcha.Issue {
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+		opt := &option{root: root, noConfig: true, export: "issues", tracker: tracker}
+		buf, errbuf := bytes.NewBufferString(""), bytes.NewBufferString("")
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+		var issues []*gotcha.Issue
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			issue := new(gotcha.Issue)
+			if err := dec.Decode(issue); err != nil {
+				t.Fatal(err)
+			}
+			issues = append(issues, issue)
+		}
+		return issues
+	}
+
+	first := export(t, "// TODO: one\n// TODO: two\n")
+	if len(first) != 2 || first[0].Action != gotcha.IssueOpen || first[0].ID != "1" || first[1].ID != "2" {
+		t.Fatalf("unexpected issues: %#v", first)
+	}
+	if first[0].Path != "main.go" || first[0].Title != "TODO: one" || first[0].Line != 1 {
+		t.Errorf("unexpected issue: %#v", first[0])
+	}
+
+	// rerun with shifted lines
+	if issues := export(t, "package main\n// TODO: one\n// TODO: two\n"); len(issues) != 0 {
+		t.Errorf("expected no issues but %#v", issues)
+	}
+
+	// resolved and new
+	issues := export(t, "// TODO: two\n// TODO: three\n")
+	if len(issues) != 2 {
+		t.Fatalf("unexpected issues: %#v", issues)
+	}
+	if issues[0].Action != gotcha.IssueOpen || issues[0].ID != "3" || issues[0].Text != "three" {
+		t.Errorf("unexpected new issue: %#v", issues[0])
+	}
+	if issues[1].Action != gotcha.IssueClose || issues[1].ID != "1" || issues[1].Text != "one" {
+		t.Errorf("unexpected resolved issue: %#v", issues[1])
+	}
+
+	tr, err := gotcha.OpenFileTracker(tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Issues()) != 2 || tr.NextID != 4 {
+		t.Errorf("unexpected tracker: %#v", tr)
+	}
+
+	// the tracker is not gathered without -tracker
+	opt := &option{root: root, noConfig: true, replace: "$1(alice)$2", dryRun: true}
+	buf, errbuf := bytes.NewBufferString(""), bytes.NewBufferString("")
+	if exit := run(buf, errbuf, opt); exit != ValidExit {
+		t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+	}
+	if strings.Contains(buf.String(), "tracker.json") {
+		t.Errorf("unexpected diff of tracker: %s", buf)
+	}
+}
@@ -2,19 +2,27 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/yaeshimo/go-utils/gotcha"
 )
 
 // version and cmd name
 const (
-	Version = "0.3.1dev"
-	Name    = "gotcha"
+	Version = gotcha.Version
+	Name    = gotcha.Name
 )
 
 // exit code
@@ -22,12 +30,16 @@ const (
 	ValidExit = iota
 	ErrInitialize
 	ErrRun
+	ErrCheck
 )
 
 type option struct {
 	version  bool
 	root     string
-	word     string
+	roots    []string
+	words    []string
+	group    bool
+	format   string
 	abort    bool
 	out      string
 	force    bool
@@ -42,8 +54,25 @@ type option struct {
 	ignoreBases string
 	ignoreTypes string
 
-	trim bool
-	add  uint
+	trim    bool
+	add     uint
+	before  uint
+	context uint
+
+	commentsOnly bool
+	symbols      bool
+	binary       string
+	decompress   bool
+	encoding     string
+	// by extension, from configuration
+	encodings map[string]string
+	// patterns of annotation, from configuration
+	annotations []string
+
+	blame     bool
+	olderThan string
+	sort      string
+	ordered   string
 
 	maxRune int
 
@@ -52,37 +81,96 @@ type option struct {
 	cache   bool
 
 	verbose bool
+
+	noConfig bool
+	noIgnore bool
+
+	// walk
+	follow        bool
+	oneFileSystem bool
+	maxDepth      uint
+
+	// limit to git diff
+	gitDiff   string
+	staged    bool
+	addedOnly bool
+
+	// CI gate
+	baseline string
+	check    bool
+	maxTotal uint
+
+	// export
+	export  string
+	tracker string
+
+	// filters by annotation
+	owner   string
+	issue   string
+	overdue bool
+
+	// rewrite matched words
+	replace string
+	dryRun  bool
+
+	// re-scan changed files until interrupt
+	watch bool
+
+	// decoration of text output
+	color        string
+	hyperlink    bool
+	editorFormat bool
+
+	// list of files, "-" is stdin
+	filesFrom string
+	null      bool
+
+	// explicitly specified flags, have priority over configuration
+	set map[string]bool
 }
 
 var opt = &option{}
 
-// TODO: consider default ignores
-// Default Ignores
-var (
-	IgnoreDirs = []string{
-		".git",
-		".cache",
-	}
-	// TODO: implementation to read .gotcha
-	IgnoreBases = []string{
-		".gotcha",
-	}
-	IgnoreTypes = []string{
-		".iso", ".img",
-		".log", ".prof",
-		".pgp", ".ttf", ".pdf",
-		".jpg", ".jpeg", ".png", ".ico", ".gif",
-		".mp4",
-		".mp3", ".ogg", ".wav", ".au",
-		".so", ".mo", ".a", ".o", ".pyc", ".exe", ".efi",
-		".gz", ".xz", ".tar", ".bz", ".bz2", ".db", ".tgz", ".zip",
+// wordsValue is flag.Value for repeatable "-word"
+// first Set replace the default words
+type wordsValue struct {
+	words   *[]string
+	changed bool
+}
+
+func newWordsValue(p *[]string, def []string) *wordsValue {
+	*p = def
+	return &wordsValue{words: p}
+}
+
+func (v *wordsValue) String() string {
+	if v.words == nil {
+		return ""
 	}
-)
+	return strings.Join(*v.words, ", ")
+}
 
+func (v *wordsValue) Set(s string) error {
+	if s == "" {
+		return errors.New("empty word")
+	}
+	if !v.changed {
+		*v.words = nil
+		v.changed = true
+	}
+	*v.words = append(*v.words, s)
+	return nil
+}
+
+// TODO: consider default ignores
 func init() {
 	flag.BoolVar(&opt.version, "version", false, "print version "+`"`+Version+`"`)
 	flag.StringVar(&opt.root, "root", "", "specify search root directory")
-	flag.StringVar(&opt.word, "word", "TODO: ", "specify search word")
+	flag.StringVar(&opt.filesFrom, "files-from", "", "read list of files and directories to search from the file, \"-\" is stdin")
+	flag.BoolVar(&opt.null, "0", false, "with \"-files-from\", list is separated by NUL instead of newline")
+	flag.Var(newWordsValue(&opt.words, []string{"TODO: "}), "word", "specify search word. can be repeated for multiple tags")
+	flag.BoolVar(&opt.group, "group", false, "output with grouping by tag")
+	flag.StringVar(&opt.format, "format", "text", "specify output format "+strings.Join(gotcha.Formats, "|"))
 	flag.StringVar(&opt.out, "out", "", "specify output file")
 	flag.BoolVar(&opt.force, "force", false, "accept overwrite for \"-out\"")
 	flag.BoolVar(&opt.total, "total", false, "prints total number of contents")
@@ -90,21 +178,251 @@ func init() {
 
 	sep := string(filepath.ListSeparator)
 	flag.StringVar(&opt.types, "types", "", "specify filetypes. separator is '"+sep+"'")
-	flag.StringVar(&opt.ignoreDirs, "ignore-dirs", strings.Join(IgnoreDirs, sep), "specify ignore directories. separator is '"+sep+"'")
-	flag.StringVar(&opt.ignoreBases, "ignore-bases", strings.Join(IgnoreBases, sep), "specify ignore basenames. separator is '"+sep+"'")
-	flag.StringVar(&opt.ignoreTypes, "ignore-types", strings.Join(IgnoreTypes, sep), "specify ignore file types. separator is '"+sep+"'")
+	flag.StringVar(&opt.ignoreDirs, "ignore-dirs", strings.Join(gotcha.IgnoreDirs, sep), "specify ignore directories. separator is '"+sep+"'")
+	flag.StringVar(&opt.ignoreBases, "ignore-bases", strings.Join(gotcha.IgnoreBases, sep), "specify ignore basenames. separator is '"+sep+"'")
+	flag.StringVar(&opt.ignoreTypes, "ignore-types", strings.Join(gotcha.IgnoreTypes, sep), "specify ignore file types. separator is '"+sep+"'")
+
+	flag.BoolVar(&opt.commentsOnly, "comments-only", false, "drop matches of outside comments, language is selected by file extension")
+
+	flag.BoolVar(&opt.symbols, "symbols", false, "attach enclosing function or type to each matches, Go is parsed and some languages are by indent")
+
+	flag.StringVar(&opt.binary, "binary", "skip", "specify mode of binary files "+strings.Join(gotcha.Binaries, "|"))
+	flag.StringVar(&opt.encoding, "encoding", gotcha.EncodingAuto, "specify encoding of files, "+gotcha.EncodingAuto+" detect BOM, UTF-16, Shift_JIS and EUC-JP. other than UTF-8 and UTF-16 require iconv command")
+	flag.BoolVar(&opt.decompress, "decompress", false, "read gzip, bzip2 and xz files and members of tar and zip archives")
+
+	flag.BoolVar(&opt.blame, "blame", false, "attach author and date of git blame to each matches")
+	flag.StringVar(&opt.olderThan, "older-than", "", "report only matches of older than duration e.g. 90d, 2w, 36h. implies -blame")
+	flag.StringVar(&opt.sort, "sort", "", "specify sort order "+strings.Join(gotcha.Sorts, "|")+", default is order of walk. age implies -blame")
+	flag.StringVar(&opt.color, "color", "auto", "highlight path, line number and the word "+strings.Join(AutoModes, "|")+", auto is color if output is terminal and NO_COLOR is not set")
+	flag.BoolVar(&opt.hyperlink, "hyperlink", false, "link paths and line numbers to file URL by OSC 8 escape sequence")
+	flag.BoolVar(&opt.editorFormat, "editor-format", false, "output \"path:line:col: text\" per match for quickfix of editors")
+	flag.StringVar(&opt.ordered, "ordered", "auto", "output in lexical order of walk "+strings.Join(AutoModes, "|")+", auto is ordered if output is not terminal")
+
+	flag.StringVar(&opt.owner, "owner", "", "report only matches of the owner e.g. alice of \"TODO(alice, 2026-12-01): \"")
+	flag.StringVar(&opt.issue, "issue", "", "report only matches of the issue reference e.g. #1234 of \"TODO(#1234)\"")
+	flag.BoolVar(&opt.overdue, "overdue", false, "report only matches of past the due date, exit with "+strconv.Itoa(ErrCheck)+" if exists")
 
 	flag.BoolVar(&opt.trim, "trim", false, "trim the word on output")
 	flag.UintVar(&opt.add, "add", 0, "specify number of lines of after find the word")
+	flag.UintVar(&opt.before, "before", 0, "specify number of lines of before find the word")
+	flag.UintVar(&opt.context, "context", 0, "specify number of lines of before and after find the word")
 
-	flag.IntVar(&opt.maxRune, "max", 256, "specify characters limit")
+	flag.IntVar(&opt.maxRune, "max", 256, "specify characters limit of output lines, long lines are excerpted around the word")
 	flag.BoolVar(&opt.abort, "abort", false, "if exists errors then abort process")
 
 	flag.UintVar(&opt.nworker, "nworker", 0, "specify limitation of gather worker")
 	flag.BoolVar(&opt.sync, "sync", false, "for debug: run on sync")
 	flag.BoolVar(&opt.cache, "cache", false, "use data cache")
 
+	flag.StringVar(&opt.replace, "replace", "", "rewrite matched words by template in place, $1 is word without trailing punctuation and $2 is the trailing e.g. '$1(alice)$2'")
+	flag.BoolVar(&opt.dryRun, "dry-run", false, "print unified diff of \"-replace\" instead of rewrite")
+
+	flag.BoolVar(&opt.watch, "watch", false, "after scan, watch directories and print added and removed matches of changed files until interrupt. linux only")
+
 	flag.BoolVar(&opt.verbose, "verbose", false, "verbose output")
+
+	flag.BoolVar(&opt.noConfig, "no-config", false, "do not read "+gotcha.ConfigName+" of root and parents")
+	flag.BoolVar(&opt.noIgnore, "no-ignore", false, "do not respect "+strings.Join(gotcha.IgnoreFiles, ", "))
+
+	flag.BoolVar(&opt.follow, "follow", false, "walk into symbolic links, loops are detected by inode")
+	flag.BoolVar(&opt.oneFileSystem, "one-file-system", false, "do not walk into directories of other file system than the root")
+	flag.UintVar(&opt.maxDepth, "max-depth", 0, "specify limit of depth of directories, 1 is files of the root only, 0 is unlimited")
+
+	flag.StringVar(&opt.gitDiff, "git-diff", "", "limit to changed files of revision range e.g. main...HEAD")
+	flag.BoolVar(&opt.staged, "staged", false, "limit to changed files of staged")
+	flag.BoolVar(&opt.addedOnly, "added-only", false, "with \"-git-diff\" or \"-staged\", report only matches on added lines")
+
+	flag.StringVar(&opt.baseline, "baseline", "", "record current matches to the file, with \"-check\" compare to the file")
+	flag.BoolVar(&opt.check, "check", false, "exit with "+strconv.Itoa(ErrCheck)+" if exists matches of missing from \"-baseline\"")
+	flag.StringVar(&opt.export, "export", "", "export matches instead of output, "+strings.Join(Exports, "|"))
+	flag.StringVar(&opt.tracker, "tracker", "", "with \"-export issues\", record issues to the file and export only new and resolved")
+	flag.UintVar(&opt.maxTotal, "max-total", 0, "exit with "+strconv.Itoa(ErrCheck)+" if total matches exceed this, 0 is unlimited")
+}
+
+// applyConfig overwrite opt by conf, explicitly specified flags are kept
+func (opt *option) applyConfig(conf *gotcha.Config) {
+	sep := string(filepath.ListSeparator)
+	appendList := func(list string, add []string) string {
+		if len(add) == 0 {
+			return list
+		}
+		if list == "" {
+			return strings.Join(add, sep)
+		}
+		return list + sep + strings.Join(add, sep)
+	}
+	if len(conf.Words) != 0 && !opt.set["word"] {
+		opt.words = conf.Words
+	}
+	if len(conf.Types) != 0 && !opt.set["types"] {
+		opt.types = strings.Join(conf.Types, sep)
+	}
+	if !opt.set["ignore-dirs"] {
+		opt.ignoreDirs = appendList(opt.ignoreDirs, conf.IgnoreDirs)
+	}
+	if !opt.set["ignore-bases"] {
+		opt.ignoreBases = appendList(opt.ignoreBases, conf.IgnoreBases)
+	}
+	if !opt.set["ignore-types"] {
+		opt.ignoreTypes = appendList(opt.ignoreTypes, conf.IgnoreTypes)
+	}
+	if conf.Max != nil && !opt.set["max"] {
+		opt.maxRune = *conf.Max
+	}
+	if conf.Add != nil && !opt.set["add"] {
+		opt.add = *conf.Add
+	}
+	if conf.Encoding != "" && !opt.set["encoding"] {
+		opt.encoding = conf.Encoding
+	}
+	opt.encodings = conf.Encodings
+	opt.annotations = conf.Annotations
+}
+
+// AutoModes available modes of switch by terminal
+var AutoModes = []string{"auto", "always", "never"}
+
+// Exports available export modes
+var Exports = []string{"issues"}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor reports whether color the output by mode of AutoModes
+// auto is color if tty and NO_COLOR is not set
+func useColor(mode string, tty bool) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return tty && os.Getenv("NO_COLOR") == ""
+}
+
+// diffDir return directory for run git diff on root
+func diffDir(root string) (string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return root, nil
+	}
+	return filepath.Dir(root), nil
+}
+
+// readFileList read list of paths from r, separated by newline or NUL if null
+// empty entries are skipped
+func readFileList(r io.Reader, null bool) ([]string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sep := "\n"
+	if null {
+		sep = "\x00"
+	}
+	var paths []string
+	for _, s := range strings.Split(string(b), sep) {
+		if !null {
+			s = strings.TrimSuffix(s, "\r")
+		}
+		if s != "" {
+			paths = append(paths, s)
+		}
+	}
+	return paths, nil
+}
+
+// searchRoots return roots of "-root", arguments and "-files-from"
+// default is current directory, stdin is read for "-files-from -"
+func searchRoots(opt *option, stdin io.Reader) ([]string, error) {
+	var roots []string
+	if opt.root != "" {
+		roots = append(roots, opt.root)
+	}
+	roots = append(roots, opt.roots...)
+	if opt.filesFrom != "" {
+		r := stdin
+		if opt.filesFrom != "-" {
+			f, err := os.Open(opt.filesFrom)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		paths, err := readFileList(r, opt.null)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, paths...)
+	} else if len(roots) == 0 {
+		roots = append(roots, ".")
+	}
+	return roots, nil
+}
+
+// gate is CI gate of run, return exit code
+func gate(errw io.Writer, g *gotcha.Gotcha, result *gotcha.Result, opt *option) int {
+	exitCode := ValidExit
+	switch {
+	case opt.baseline != "" && opt.check:
+		base, err := gotcha.ReadBaseline(opt.baseline)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			return ErrRun
+		}
+		news := g.NewMatches(base, opt.root, result.Matches)
+		for _, m := range news {
+			fmt.Fprintf(errw, "new: %s:%d: %s\n", m.Path, m.Line, m.Text)
+		}
+		if len(news) != 0 {
+			exitCode = ErrCheck
+		}
+	case opt.baseline != "":
+		if err := g.NewBaseline(opt.root, result.Matches).WriteFile(opt.baseline); err != nil {
+			fmt.Fprintln(errw, err)
+			return ErrRun
+		}
+	}
+	// overdue fail even if in baseline
+	if opt.overdue || opt.check {
+		now := time.Now()
+		var n int
+		for _, m := range result.Matches {
+			if m.Annotation.Overdue(now) {
+				fmt.Fprintf(errw, "overdue: %s:%d: %s\n", m.Path, m.Line, m.Text)
+				n++
+			}
+		}
+		if n != 0 {
+			exitCode = ErrCheck
+		}
+	}
+	if opt.maxTotal > 0 && result.Lines > opt.maxTotal {
+		fmt.Fprintf(errw, "total matches %d exceeds %d\n", result.Lines, opt.maxTotal)
+		exitCode = ErrCheck
+	}
+	return exitCode
 }
 
 func run(w, errw io.Writer, opt *option) (exitCode int) {
@@ -114,15 +432,40 @@ func run(w, errw io.Writer, opt *option) (exitCode int) {
 		return
 	}
 
+	if opt.null && opt.filesFrom == "" {
+		fmt.Fprintln(errw, "\"-0\" require \"-files-from\"")
+		exitCode = ErrInitialize
+		return
+	}
+	roots, err := searchRoots(opt, os.Stdin)
+	if err != nil {
+		fmt.Fprintln(errw, err)
+		exitCode = ErrInitialize
+		return
+	}
+	// base of configuration, git diff, baseline and export
+	switch {
+	case len(roots) == 1:
+		opt.root = roots[0]
+	case opt.root == "":
+		opt.root = "."
+	}
+
 	// abs for root
 	if opt.fullpath {
-		abs, err := filepath.Abs(opt.root)
-		if err != nil {
-			fmt.Fprintln(errw, err)
-			exitCode = ErrInitialize
-			return
+		for i, root := range append([]string{opt.root}, roots...) {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				fmt.Fprintln(errw, err)
+				exitCode = ErrInitialize
+				return
+			}
+			if i == 0 {
+				opt.root = abs
+			} else {
+				roots[i-1] = abs
+			}
 		}
-		opt.root = abs
 	}
 
 	// out to file
@@ -142,6 +485,9 @@ func run(w, errw io.Writer, opt *option) (exitCode int) {
 		w = f
 	}
 
+	// before buffer
+	tty := isTerminal(w)
+
 	// use buffer
 	if opt.cache {
 		origw := w
@@ -160,6 +506,167 @@ func run(w, errw io.Writer, opt *option) (exitCode int) {
 		}()
 	}
 
+	// project configuration
+	if !opt.noConfig {
+		conf, err := gotcha.LoadConfig(opt.root)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			exitCode = ErrInitialize
+			return
+		}
+		if conf != nil {
+			opt.applyConfig(conf)
+		}
+	}
+
+	if opt.format == "" {
+		opt.format = "text"
+	}
+	if !contains(gotcha.Formats, opt.format) {
+		fmt.Fprintln(errw, "unknown format: ", opt.format)
+		exitCode = ErrInitialize
+		return
+	}
+
+	if opt.binary == "" {
+		opt.binary = "skip"
+	}
+	if !contains(gotcha.Binaries, opt.binary) {
+		fmt.Fprintln(errw, "unknown binary mode: ", opt.binary)
+		exitCode = ErrInitialize
+		return
+	}
+
+	if opt.sort != "" && !contains(gotcha.Sorts, opt.sort) {
+		fmt.Fprintln(errw, "unknown sort: ", opt.sort)
+		exitCode = ErrInitialize
+		return
+	}
+	if opt.ordered == "" {
+		opt.ordered = "auto"
+	}
+	if !contains(AutoModes, opt.ordered) {
+		fmt.Fprintln(errw, "unknown ordered: ", opt.ordered)
+		exitCode = ErrInitialize
+		return
+	}
+
+	if opt.color == "" {
+		opt.color = "auto"
+	}
+	if !contains(AutoModes, opt.color) {
+		fmt.Fprintln(errw, "unknown color: ", opt.color)
+		exitCode = ErrInitialize
+		return
+	}
+	for _, c := range []struct {
+		name string
+		set  bool
+	}{
+		{"-hyperlink", opt.hyperlink},
+		{"-editor-format", opt.editorFormat},
+	} {
+		if c.set && opt.format != "text" {
+			fmt.Fprintf(errw, "\"%s\" is for text output, can not use with \"-format\": %s\n", c.name, opt.format)
+			exitCode = ErrInitialize
+			return
+		}
+	}
+
+	if opt.check && opt.baseline == "" {
+		fmt.Fprintln(errw, "\"-check\" require \"-baseline\"")
+		exitCode = ErrInitialize
+		return
+	}
+
+	if opt.dryRun && opt.replace == "" {
+		fmt.Fprintln(errw, "\"-dry-run\" require \"-replace\"")
+		exitCode = ErrInitialize
+		return
+	}
+	if opt.dryRun && opt.format != "text" {
+		fmt.Fprintln(errw, "\"-dry-run\" output unified diff, can not use with \"-format\": ", opt.format)
+		exitCode = ErrInitialize
+		return
+	}
+
+	if opt.watch {
+		for _, c := range []struct {
+			name     string
+			conflict bool
+		}{
+			{"-replace", opt.replace != ""},
+			{"-baseline", opt.baseline != ""},
+			{"-export", opt.export != ""},
+			{"-cache", opt.cache},
+			{"-format", opt.format != "text"},
+			{"-follow", opt.follow},
+		} {
+			if c.conflict {
+				fmt.Fprintf(errw, "\"-watch\" can not use with \"%s\"\n", c.name)
+				exitCode = ErrInitialize
+				return
+			}
+		}
+	}
+
+	if opt.watch && len(roots) != 1 {
+		fmt.Fprintln(errw, "\"-watch\" require a single root")
+		exitCode = ErrInitialize
+		return
+	}
+
+	var annotations []*regexp.Regexp
+	if len(opt.annotations) != 0 {
+		res, err := gotcha.CompileAnnotations(opt.annotations)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			exitCode = ErrInitialize
+			return
+		}
+		annotations = res
+	}
+
+	if opt.export != "" && !contains(Exports, opt.export) {
+		fmt.Fprintln(errw, "unknown export: ", opt.export)
+		exitCode = ErrInitialize
+		return
+	}
+	var tracker gotcha.Tracker
+	if opt.tracker != "" {
+		tr, err := gotcha.OpenFileTracker(opt.tracker)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			exitCode = ErrInitialize
+			return
+		}
+		tracker = tr
+	}
+
+	var changes *gotcha.Changes
+	if opt.gitDiff != "" || opt.staged {
+		dir, err := diffDir(opt.root)
+		if err == nil {
+			changes, err = gotcha.GitDiff(dir, opt.gitDiff, opt.staged)
+		}
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			exitCode = ErrInitialize
+			return
+		}
+	}
+
+	var olderThan time.Duration
+	if opt.olderThan != "" {
+		d, err := gotcha.ParseAge(opt.olderThan)
+		if err != nil {
+			fmt.Fprintln(errw, err)
+			exitCode = ErrInitialize
+			return
+		}
+		olderThan = d
+	}
+
 	/// init Gotcha
 	makeBoolMap := func(list string) map[string]bool {
 		m := make(map[string]bool)
@@ -168,55 +675,136 @@ func run(w, errw io.Writer, opt *option) (exitCode int) {
 		}
 		return m
 	}
-	g := NewGotcha()
+	g := gotcha.NewGotcha()
 	g.W = w
-	g.Word = opt.word
+	if len(opt.words) != 0 {
+		g.Words = opt.words
+	}
 	g.Abort = opt.abort
+	g.Group = opt.group
+	g.Format = opt.format
+	g.CommentsOnly = opt.commentsOnly
+	g.Symbols = opt.symbols
+	g.Blame = opt.blame || olderThan > 0 || opt.sort == "age"
+	g.OlderThan = olderThan
+	g.Sort = opt.sort
+	g.Ordered = opt.ordered == "always" || (opt.ordered == "auto" && !tty)
+	g.Color = opt.format == "text" && useColor(opt.color, tty)
+	g.Hyperlink = opt.hyperlink
+	g.EditorFormat = opt.editorFormat
+	g.GitIgnore = !opt.noIgnore
+	g.Follow = opt.follow
+	g.OneFileSystem = opt.oneFileSystem
+	g.MaxDepth = opt.maxDepth
+	g.Changes = changes
+	g.AddedOnly = opt.addedOnly
+	g.Binary = opt.binary
+	g.Decompress = opt.decompress
+	if opt.encoding != "" {
+		g.Encoding = opt.encoding
+	}
+	for ext, enc := range opt.encodings {
+		g.EncodingsMap[ext] = enc
+	}
+	g.Warn.SetOutput(errw)
+	if annotations != nil {
+		g.Annotations = annotations
+	}
+	g.Owner = opt.owner
+	g.Issue = opt.issue
+	g.Overdue = opt.overdue
+	g.Replace = opt.replace
+	g.DryRun = opt.dryRun
+	g.Keep = opt.baseline != "" || opt.export != "" || opt.overdue
+	if opt.export != "" {
+		// matches are written by export
+		g.W = ioutil.Discard
+	}
 	g.TypesMap = makeBoolMap(opt.types)
 	g.IgnoreDirsMap = makeBoolMap(opt.ignoreDirs)
 	g.IgnoreBasesMap = makeBoolMap(opt.ignoreBases)
 	g.IgnoreTypesMap = makeBoolMap(opt.ignoreTypes)
+	// do not gather own records
+	for _, path := range []string{opt.baseline, opt.tracker} {
+		if path != "" {
+			g.IgnoreBasesMap[filepath.Base(path)] = true
+		}
+	}
 	g.MaxRune = opt.maxRune
 	g.Add = opt.add
+	g.Before = opt.before
+	if opt.context > g.Add {
+		g.Add = opt.context
+	}
+	if opt.context > g.Before {
+		g.Before = opt.context
+	}
 	if opt.verbose {
 		g.Log.SetOutput(errw)
 	} else {
 		g.Log.SetOutput(ioutil.Discard)
 	}
 
-	info, err := os.Stat(opt.root)
-	if err != nil {
-		fmt.Fprintln(errw, err)
+	// missing roots of e.g. "-files-from" are skipped and fail at the end
+	var (
+		found   []string
+		missing bool
+	)
+	for _, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			fmt.Fprintln(errw, err)
+			missing = true
+			continue
+		}
+		found = append(found, root)
+	}
+	if len(found) == 0 {
 		exitCode = ErrInitialize
 		return
 	}
+	roots = found
+	// stop the work by interrupt, matches of until then are output
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// sync or async
+	var result *gotcha.Result
 	switch {
-	case info.IsDir():
-		// sync or async
-		if opt.sync {
-			exitCode = g.SyncWorkGo(opt.root)
-		} else {
-			exitCode = g.WorkGo(opt.root, opt.nworker)
-		}
-	case info.Mode().IsRegular():
-		res := g.gather(opt.root)
-		if res.err != nil {
-			fmt.Fprintln(errw, res)
-			exitCode = ErrRun
+	case opt.watch:
+		result = g.Watch(ctx, opt.root, opt.nworker, func(c *gotcha.Change) error {
+			return printChange(w, c)
+		})
+	case opt.sync:
+		result = g.SyncWorkGoRoots(ctx, roots)
+	default:
+		result = g.WorkGoRoots(ctx, roots, opt.nworker)
+	}
+	if len(result.Errors) != 0 {
+		for _, err := range result.Errors {
+			fmt.Fprintln(errw, err)
 		}
-		if err := res.Fwrite(g.W); err != nil {
+		exitCode = ErrRun
+	}
+	if missing {
+		exitCode = ErrRun
+	}
+
+	// export
+	if opt.export == "issues" {
+		if err := g.ExportIssues(w, opt.root, result.Matches, tracker); err != nil {
 			fmt.Fprintln(errw, err)
 			exitCode = ErrRun
 		}
-	default:
-		fmt.Fprintln(errw, "invalid file type")
-		exitCode = ErrRun
+		g.W = w
+	}
+
+	// CI gate
+	if code := gate(errw, g, result, opt); code != ValidExit {
+		exitCode = code
 	}
 
 	// append total
 	if opt.total {
-		_, err = g.PrintTotal()
-		if err != nil {
+		if _, err := g.PrintTotal(); err != nil {
 			fmt.Fprint(errw, err)
 			exitCode = ErrRun
 		}
@@ -224,19 +812,30 @@ func run(w, errw io.Writer, opt *option) (exitCode int) {
 	return exitCode
 }
 
-func main() {
-	flag.Parse()
-	if flag.NArg() != 0 {
-		if opt.root == "" {
-			if flag.NArg() == 1 {
-				opt.root = flag.Arg(0)
-			} else {
-				opt.root = "."
-			}
-		} else {
-			fmt.Fprintln(os.Stderr, "unknown arguments: ", flag.Args())
-			os.Exit(ErrInitialize)
+// printChange print matches of c with prefix "+" or "-" and running totals
+func printChange(w io.Writer, c *gotcha.Change) error {
+	for _, m := range c.Removed {
+		if _, err := fmt.Fprintf(w, "-%s:L%d:%s\n", m.Path, m.Line, m.Text); err != nil {
+			return err
 		}
 	}
+	for _, m := range c.Added {
+		if _, err := fmt.Fprintf(w, "+%s:L%d:%s\n", m.Path, m.Line, m.Text); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "files %d lines %d\n\n", c.Files, c.Lines)
+	return err
+}
+
+func main() {
+	// subcommands, a directory named "history" is searched by "./history"
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistory(os.Stdout, os.Stderr, os.Args[2:]))
+	}
+	flag.Parse()
+	opt.set = make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { opt.set[f.Name] = true })
+	opt.roots = flag.Args()
 	os.Exit(run(os.Stdout, os.Stderr, opt))
 }
@@ -5,12 +5,36 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/yaeshimo/go-utils/gotcha"
 )
 
 var TestRoot = "t"
 
+// gitRun run git in dir as alice, date of author and committer if not empty
+// global and system config are ignored e.g. signing of commits
+func gitRun(t *testing.T, dir, date string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+	)
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	}
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, b)
+	}
+}
+
 func TestRun(t *testing.T) {
 	testRoot := filepath.Join(TestRoot, "run")
 	if err := os.MkdirAll(testRoot, 0777); err != nil {
@@ -74,6 +98,63 @@ func TestRun(t *testing.T) {
 		opt.sync = false
 	})
 
+	t.Run("unknown format", func(t *testing.T) {
+		opt := newopt()
+		buf, errbuf := newbufs()
+		opt.root = testRoot
+		opt.format = "unknown"
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+
+	t.Run("unknown ordered", func(t *testing.T) {
+		opt := newopt()
+		buf, errbuf := newbufs()
+		opt.root = testRoot
+		opt.ordered = "unknown"
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+
+	t.Run("dry run without replace", func(t *testing.T) {
+		opt := newopt()
+		buf, errbuf := newbufs()
+		opt.root = testRoot
+		opt.dryRun = true
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+		opt.replace = "$1(x)$2"
+		opt.format = "json"
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+
+	t.Run("watch with conflict", func(t *testing.T) {
+		opt := newopt()
+		buf, errbuf := newbufs()
+		opt.root = testRoot
+		opt.watch = true
+		opt.format = "json"
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d but exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+
+	t.Run("editor format with json", func(t *testing.T) {
+		opt := newopt()
+		opt.root = testRoot
+		opt.format = "json"
+		opt.editorFormat = true
+		buf, errbuf := newbufs()
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+
 	t.Run("version", func(t *testing.T) {
 		opt := newopt()
 		buf, errbuf := newbufs()
@@ -170,4 +251,169 @@ func TestRun(t *testing.T) {
 			t.Errorf("exp=%#v out=%#v opt=%#v", exp, buf, opt)
 		}
 	})
+
+	t.Run("multiple roots", func(t *testing.T) {
+		root := filepath.Join(testRoot, "multiple_roots")
+		for _, dir := range []string{"a", "b"} {
+			if err := os.MkdirAll(filepath.Join(root, dir), 0777); err != nil {
+				t.Fatal(err)
+			}
+		}
+		defer os.RemoveAll(root)
+		a := filepath.Join(root, "a", "x.txt")
+		b := filepath.Join(root, "b", "y.txt")
+		for _, path := range []string{a, b} {
+			if err := ioutil.WriteFile(path, []byte("TODO: hello"), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		exp := b + "\nL1:TODO: hello\n\n" + a + "\nL1:TODO: hello\n\n"
+
+		// overlapped roots are walked once
+		opt := newopt()
+		buf, errbuf := newbufs()
+		opt.roots = []string{b, filepath.Join(root, "a"), a}
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+		if exp != buf.String() {
+			t.Errorf("exp=%#v out=%#v", exp, buf.String())
+		}
+
+		// files from the list of NUL separated
+		list := filepath.Join(root, "list")
+		if err := ioutil.WriteFile(list, []byte(b+"\x00"+a+"\x00"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		opt = newopt()
+		buf, errbuf = newbufs()
+		opt.filesFrom = list
+		opt.null = true
+		if exit := run(buf, errbuf, opt); exit != ValidExit {
+			t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+		}
+		if exp != buf.String() {
+			t.Errorf("exp=%#v out=%#v", exp, buf.String())
+		}
+
+		// missing file of the list is skipped
+		opt = newopt()
+		buf, errbuf = newbufs()
+		opt.roots = []string{a, filepath.Join(root, "missing"), b}
+		if exit := run(buf, errbuf, opt); exit != ErrRun {
+			t.Errorf("expected exit=%d exit=%d errbuf=%s", ErrRun, exit, errbuf)
+		}
+		if exp := a + "\nL1:TODO: hello\n\n" + b + "\nL1:TODO: hello\n\n"; exp != buf.String() {
+			t.Errorf("exp=%#v out=%#v", exp, buf.String())
+		}
+		if !strings.Contains(errbuf.String(), "missing") {
+			t.Errorf("missing file is not reported: %s", errbuf)
+		}
+
+		// all missing
+		opt = newopt()
+		buf, errbuf = newbufs()
+		opt.roots = []string{filepath.Join(root, "missing")}
+		if exit := run(buf, errbuf, opt); exit != ErrInitialize {
+			t.Errorf("expected exit=%d exit=%d errbuf=%s", ErrInitialize, exit, errbuf)
+		}
+	})
+}
+
+func TestPrintChange(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	c := &gotcha.Change{
+		Path:    "a.go",
+		Added:   []*gotcha.Match{{Path: "a.go", Line: 3, Text: "TODO: new"}},
+		Removed: []*gotcha.Match{{Path: "a.go", Line: 2, Text: "TODO: old"}},
+		Files:   1,
+		Lines:   4,
+	}
+	if err := printChange(buf, c); err != nil {
+		t.Fatal(err)
+	}
+	exp := "-a.go:L2:TODO: old\n+a.go:L3:TODO: new\nfiles 1 lines 4\n\n"
+	if buf.String() != exp {
+		t.Errorf("exp=%q out=%q", exp, buf)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	tests := []struct {
+		mode    string
+		tty     bool
+		noColor string
+		exp     bool
+	}{
+		{mode: "auto", tty: true, exp: true},
+		{mode: "auto", tty: false, exp: false},
+		{mode: "auto", tty: true, noColor: "1", exp: false},
+		{mode: "always", tty: false, noColor: "1", exp: true},
+		{mode: "never", tty: true, exp: false},
+	}
+	for _, test := range tests {
+		os.Setenv("NO_COLOR", test.noColor)
+		if out := useColor(test.mode, test.tty); out != test.exp {
+			t.Errorf("%+v: out=%v", test, out)
+		}
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		in   string
+		null bool
+		exp  []string
+	}{
+		{in: "", exp: nil},
+		{in: "a\nb c\n\n", exp: []string{"a", "b c"}},
+		{in: "a\r\nb", exp: []string{"a", "b"}},
+		{in: "a\nb\x00c\x00", null: true, exp: []string{"a\nb", "c"}},
+	}
+	for _, test := range tests {
+		out, err := readFileList(strings.NewReader(test.in), test.null)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(test.exp, out) {
+			t.Errorf("in=%q exp=%q out=%q", test.in, test.exp, out)
+		}
+	}
+}
+
+func TestSortAge(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root, err := filepath.Abs(filepath.Join(TestRoot, "sort_age"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	gitRun(t, root, "", "init", "-q")
+	for _, c := range []struct{ name, date string }{
+		{name: "old.txt", date: "2000-01-01T00:00:00Z"},
+		{name: "new.txt", date: "2026-01-01T00:00:00Z"},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(root, c.name), []byte("TODO: "+c.name+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		gitRun(t, root, c.date, "add", "-A")
+		gitRun(t, root, c.date, "commit", "-q", "-m", c.name)
+	}
+
+	// without -blame
+	buf, errbuf := new(bytes.Buffer), new(bytes.Buffer)
+	opt := &option{root: root, noConfig: true, sort: "age", format: "json"}
+	if exit := run(buf, errbuf, opt); exit != ValidExit {
+		t.Fatalf("exit=%d errbuf=%s", exit, errbuf)
+	}
+	oldAt, newAt := strings.Index(buf.String(), "old.txt"), strings.Index(buf.String(), "new.txt")
+	if oldAt == -1 || newAt == -1 || oldAt > newAt {
+		t.Errorf("expected old first: %s", buf)
+	}
 }
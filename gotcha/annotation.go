@@ -0,0 +1,117 @@
+package gotcha
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultAnnotations are patterns of Annotation of after the word
+// named groups "owner", "due" and "issue" are parsed, first matched pattern is used
+// e.g. "TODO(alice, 2026-12-01): ", "TODO(#1234)" and "TODO: (PROJ-12)"
+// also between the stem and the trailing of the word, "TODO(alice): " is matched by the word "TODO: "
+var DefaultAnnotations = []string{
+	`^\((?P<issue>#\d+|[A-Z][A-Z0-9]*-\d+)\)`,
+	`^\((?P<due>\d{4}-\d{2}-\d{2})\)`,
+	`^\((?P<owner>[^\s,()#]+)(?:\s*,\s*(?P<due>\d{4}-\d{2}-\d{2}))?(?:\s*,\s*(?P<issue>#\d+|[A-Z][A-Z0-9]*-\d+))?\)`,
+}
+
+// CompileAnnotations compile patterns of Annotation
+func CompileAnnotations(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+var defaultAnnotations, _ = CompileAnnotations(DefaultAnnotations)
+
+// Annotation is owner, due date and issue reference of a match
+type Annotation struct {
+	Owner string `json:"owner,omitempty"`
+	// date of "2006-01-02"
+	Due   string `json:"due,omitempty"`
+	Issue string `json:"issue,omitempty"`
+}
+
+// Overdue reports whether the day of now is after Due, invalid Due is not overdue
+func (a *Annotation) Overdue(now time.Time) bool {
+	if a == nil || a.Due == "" {
+		return false
+	}
+	due, err := time.ParseInLocation("2006-01-02", a.Due, now.Location())
+	if err != nil {
+		return false
+	}
+	return !now.Before(due.AddDate(0, 0, 1))
+}
+
+// annotate parse Annotation of s of after the word, nil if not matched
+func (g *Gotcha) annotate(s string) *Annotation {
+	for _, re := range g.Annotations {
+		sub := re.FindStringSubmatch(s)
+		if sub == nil {
+			continue
+		}
+		a := &Annotation{}
+		for i, name := range re.SubexpNames() {
+			if sub[i] == "" {
+				continue
+			}
+			switch name {
+			case "owner":
+				a.Owner = sub[i]
+			case "due":
+				a.Due = sub[i]
+			case "issue":
+				a.Issue = sub[i]
+			}
+		}
+		if *a != (Annotation{}) {
+			return a
+		}
+	}
+	return nil
+}
+
+// annotatedLen return length of the annotation and tail of the word at head of s, 0 if not
+// s is text of after the stem of the word, see splitWord
+func (g *Gotcha) annotatedLen(s, tail string) int {
+	for _, re := range g.Annotations {
+		loc := re.FindStringIndex(s)
+		if loc == nil || loc[0] != 0 || !strings.HasPrefix(s[loc[1]:], tail) {
+			continue
+		}
+		if g.annotate(s) != nil {
+			return loc[1] + len(tail)
+		}
+	}
+	return 0
+}
+
+// filterAnnotations drop matches of not annotated by Owner, Issue and Overdue
+func (g *Gotcha) filterAnnotations(gr *gatherRes) {
+	if g.Owner == "" && g.Issue == "" && !g.Overdue {
+		return
+	}
+	now := time.Now()
+	issue := strings.TrimPrefix(g.Issue, "#")
+	var matches []*match
+	for _, m := range gr.matches {
+		a := m.annotation
+		switch {
+		case a == nil:
+		case g.Owner != "" && a.Owner != g.Owner:
+		case g.Issue != "" && strings.TrimPrefix(a.Issue, "#") != issue:
+		case g.Overdue && !a.Overdue(now):
+		default:
+			matches = append(matches, m)
+		}
+	}
+	gr.matches = matches
+}
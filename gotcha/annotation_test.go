@@ -0,0 +1,139 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_annotate(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp *Annotation
+	}{
+		{in: "(alice, 2026-12-01): fix", exp: &Annotation{Owner: "alice", Due: "2026-12-01"}},
+		{in: "(alice): fix", exp: &Annotation{Owner: "alice"}},
+		{in: "(#1234)", exp: &Annotation{Issue: "#1234"}},
+		{in: "(PROJ-12): fix", exp: &Annotation{Issue: "PROJ-12"}},
+		{in: "(2026-12-01)", exp: &Annotation{Due: "2026-12-01"}},
+		{in: "(bob, 2026-12-01, #7) fix", exp: &Annotation{Owner: "bob", Due: "2026-12-01", Issue: "#7"}},
+		{in: ": fix (alice)", exp: nil},
+		{in: "(a b)", exp: nil},
+	}
+	g := NewGotcha()
+	for _, test := range tests {
+		if out := g.annotate(test.in); !reflect.DeepEqual(test.exp, out) {
+			t.Errorf("in=%q exp=%#v out=%#v", test.in, test.exp, out)
+		}
+	}
+
+	t.Run("patterns", func(t *testing.T) {
+		res, err := CompileAnnotations([]string{`^\[(?P<owner>\w+)\]`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Annotations = res
+		if out := g.annotate("[carol] fix"); out == nil || out.Owner != "carol" {
+			t.Errorf("unexpected annotation: %#v", out)
+		}
+		if _, err := CompileAnnotations([]string{"("}); err == nil {
+			t.Error("expected error but nil")
+		}
+	})
+}
+
+func TestAnnotationOverdue(t *testing.T) {
+	now := time.Date(2026, 12, 1, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		due string
+		exp bool
+	}{
+		{due: "2026-12-01", exp: false},
+		{due: "2026-11-30", exp: true},
+		{due: "2027-01-01", exp: false},
+		{due: "2026-13-01", exp: false},
+		{due: "", exp: false},
+	}
+	for _, test := range tests {
+		if out := (&Annotation{Due: test.due}).Overdue(now); out != test.exp {
+			t.Errorf("due=%q exp=%v out=%v", test.due, test.exp, out)
+		}
+	}
+}
+
+func TestFilterAnnotations(t *testing.T) {
+	path := filepath.Join(TestRoot, "annotation.txt")
+	contents := "TODO(alice, 2001-01-01): old\nTODO(alice, 2999-01-01): new\nTODO(#12): issue\nTODO: none\n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	tests := []struct {
+		owner, issue string
+		overdue      bool
+		exp          []uint
+	}{
+		{exp: []uint{1, 2, 3, 4}},
+		{owner: "alice", exp: []uint{1, 2}},
+		{issue: "12", exp: []uint{3}},
+		{issue: "#12", exp: []uint{3}},
+		{overdue: true, exp: []uint{1}},
+		{owner: "bob", exp: nil},
+	}
+	for _, test := range tests {
+		// annotated after the stem of the default word
+		for _, words := range [][]string{{"TODO"}, {"TODO: "}} {
+			g := NewGotcha()
+			g.Log.SetOutput(ioutil.Discard)
+			g.Words = words
+			g.Owner, g.Issue, g.Overdue = test.owner, test.issue, test.overdue
+			gr := g.gather(path)
+			var out []uint
+			for _, m := range gr.matches {
+				out = append(out, m.num)
+			}
+			if !reflect.DeepEqual(test.exp, out) {
+				t.Errorf("words=%q owner=%q issue=%q overdue=%v exp=%v out=%v", words, test.owner, test.issue, test.overdue, test.exp, out)
+			}
+		}
+	}
+}
+
+func Test_indexAnnotated(t *testing.T) {
+	tests := []struct {
+		in    string
+		index int
+		size  int
+	}{
+		{in: "// TODO(alice, 2026-12-01): x", index: 3, size: 25},
+		{in: "// TODO: (#1) x", index: 3, size: 6},
+		{in: "// TODO(alice) x TODO: y", index: 17, size: 6},
+		{in: "// TODOS(alice): x", index: -1},
+		{in: "// TODO(a b): x", index: -1},
+	}
+	g := NewGotcha()
+	for _, test := range tests {
+		index, size, tag := g.index(test.in, nil)
+		if index != test.index || index != -1 && (size != test.size || tag != "TODO: ") {
+			t.Errorf("in=%q exp=%d,%d out=%d,%d,%q", test.in, test.index, test.size, index, size, tag)
+		}
+	}
+
+	t.Run("trim", func(t *testing.T) {
+		g := NewGotcha()
+		g.Trim = true
+		gr := g.gatherReader("a.txt", "a.txt", strings.NewReader("// TODO(alice, 2026-12-01): fix\n"))
+		if len(gr.matches) != 1 {
+			t.Fatalf("unexpected matches: %#v", gr.matches)
+		}
+		m := gr.matches[0]
+		if m.text != "fix" || m.wordLen != 25 || !reflect.DeepEqual(m.annotation, &Annotation{Owner: "alice", Due: "2026-12-01"}) {
+			t.Errorf("unexpected match: %#v", m)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package gotcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// recordMark is head of JSON records written by gotcha e.g. baseline and tracker
+// files of begin with it are not gathered, stored texts are not matched by the words
+const recordMark = "{\n  \"" + Name + "\": "
+
+// marshalRecord return indented JSON of object v with recordMark
+func marshalRecord(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(b, []byte("{\n")) {
+		return nil, fmt.Errorf("record is not object: %T", v)
+	}
+	return append([]byte(recordMark+strconv.Quote(Version)+",\n"), b[2:]...), nil
+}
+
+// Baseline is recorded matches for CI gate
+// matches are identified by path, tag and normalized text without line number
+type Baseline struct {
+	Entries []*BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is a recorded match, Count is number of same matches in the file
+type BaselineEntry struct {
+	Path  string `json:"path"`
+	Tag   string `json:"tag"`
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+type baselineKey struct {
+	path string
+	tag  string
+	text string
+}
+
+func (e *BaselineEntry) key() baselineKey {
+	return baselineKey{path: e.Path, tag: e.Tag, text: e.Text}
+}
+
+// normalize return text of after the tag with collapsed spaces
+// the tag is located by the column of the match, first one if unknown
+func (g *Gotcha) normalize(m *Match) string {
+	text := m.Text
+	switch {
+	case g.Trim:
+	case m.wordLen != 0 && m.tagAt != 0 && m.tagAt-1+m.wordLen <= len(text):
+		text = text[m.tagAt-1+m.wordLen:]
+	case m.tagAt != 0 && m.tagAt <= len(text) && strings.HasPrefix(text[m.tagAt-1:], m.Tag):
+		text = text[m.tagAt-1+len(m.Tag):]
+	default:
+		if i := strings.Index(text, m.Tag); i != -1 {
+			text = text[i+len(m.Tag):]
+		}
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// baselinePath return path of relative from root with slash
+func baselinePath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel == "." {
+		rel = filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// NewBaseline make Baseline from matches of walk of root
+func (g *Gotcha) NewBaseline(root string, matches []*Match) *Baseline {
+	counts := make(map[baselineKey]*BaselineEntry)
+	base := &Baseline{Entries: []*BaselineEntry{}}
+	for _, m := range matches {
+		e := &BaselineEntry{Path: baselinePath(root, m.Path), Tag: m.Tag, Text: g.normalize(m)}
+		if c, ok := counts[e.key()]; ok {
+			c.Count++
+			continue
+		}
+		e.Count = 1
+		counts[e.key()] = e
+		base.Entries = append(base.Entries, e)
+	}
+	sort.Slice(base.Entries, func(i, j int) bool {
+		a, b := base.Entries[i], base.Entries[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Tag != b.Tag {
+			return a.Tag < b.Tag
+		}
+		return a.Text < b.Text
+	})
+	return base
+}
+
+// ReadBaseline read Baseline from file
+func ReadBaseline(path string) (*Baseline, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	base := &Baseline{}
+	if err := json.Unmarshal(b, base); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return base, nil
+}
+
+// WriteFile write Baseline to file as JSON, the file is not gathered by later walks
+func (base *Baseline) WriteFile(path string) error {
+	b, err := marshalRecord(base)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0666)
+}
+
+// NewMatches return matches of missing from base
+func (g *Gotcha) NewMatches(base *Baseline, root string, matches []*Match) []*Match {
+	counts := make(map[baselineKey]int)
+	for _, e := range base.Entries {
+		counts[e.key()] += e.Count
+	}
+	var news []*Match
+	for _, m := range matches {
+		key := baselineKey{path: baselinePath(root, m.Path), tag: m.Tag, text: g.normalize(m)}
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		news = append(news, m)
+	}
+	return news
+}
@@ -0,0 +1,25 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func Test_normalize(t *testing.T) {
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.CommentsOnly = true
+	gr := g.gatherReader("main.go", "main.go", strings.NewReader("package main\n\nvar s = \"TODO: a\" // TODO:   b\n"))
+	ms := gr.toMatches()
+	if len(ms) != 1 {
+		t.Fatalf("unexpected matches: %#v", ms)
+	}
+	if out := g.normalize(ms[0]); out != "b" {
+		t.Errorf("exp=%q out=%q", "b", out)
+	}
+	// unknown position
+	if out := g.normalize(&Match{Tag: "TODO: ", Text: "// TODO:  c  d"}); out != "c d" {
+		t.Errorf("exp=%q out=%q", "c d", out)
+	}
+}
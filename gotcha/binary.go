@@ -0,0 +1,61 @@
+package gotcha
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLen is length of head of file for detect binary
+const sniffLen = 8 * 1024
+
+// Binaries available modes for binary files
+// "skip" ignore binary files, "warn" ignore with warning, "scan" gather as text
+var Binaries = []string{"skip", "warn", "scan"}
+
+// isBinary reports whether sample of head of file looks like binary
+func isBinary(sample []byte) bool {
+	if len(sample) == 0 {
+		return false
+	}
+	if bytes.IndexByte(sample, 0) != -1 {
+		return true
+	}
+
+	// control bytes and invalid sequences of UTF-8
+	var invalid int
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// may be cut at end of sample
+			if len(sample)-i < utf8.UTFMax && !utf8.FullRune(sample[i:]) {
+				i = len(sample)
+				continue
+			}
+			invalid++
+		case r < 0x20 && !isTextControl(r), r == 0x7f:
+			invalid++
+		}
+		i += size
+	}
+	if invalid == 0 {
+		// signatures of e.g. "BM" of bmp are matched by plain text
+		return false
+	}
+	// known binary signature e.g. image, archive, pdf
+	if !strings.HasPrefix(http.DetectContentType(sample), "text/") {
+		return true
+	}
+	return invalid*10 > len(sample)
+}
+
+// isTextControl reports whether r is control character of used in text
+func isTextControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', 0x1b:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package gotcha
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_isBinary(t *testing.T) {
+	tests := []struct {
+		in  []byte
+		exp bool
+	}{
+		{in: nil, exp: false},
+		{in: []byte("TODO: hello\n"), exp: false},
+		{in: []byte("日本語のテキスト TODO: \n"), exp: false},
+		{in: []byte("TODO: hello\x00world"), exp: true},
+		{in: []byte("\x89PNG\r\n\x1a\n" + "TODO: "), exp: true},
+		{in: []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n TODO: "), exp: true},
+		// text of same head as signatures
+		{in: []byte("BMI calculator notes\nTODO: fix rounding\n"), exp: false},
+		{in: []byte("%PDF-1.4 TODO: "), exp: false},
+		{in: []byte("ID3 tags TODO: "), exp: false},
+		{in: []byte("\x1b[31mTODO: colored\x1b[0m\n"), exp: false},
+		{in: bytes.Repeat([]byte{0xff, 0xfe, 'a'}, 10), exp: true},
+		// cut multibyte at end
+		{in: []byte("TODO: 日本語")[:len("TODO: 日本語")-1], exp: false},
+	}
+	for _, test := range tests {
+		if out := isBinary(test.in); out != test.exp {
+			t.Errorf("in=%q exp=%v out=%v", test.in, test.exp, out)
+		}
+	}
+}
+
+func TestBinaryMode(t *testing.T) {
+	root := filepath.Join(TestRoot, "binary_mode")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// unknown extension
+	path := filepath.Join(root, "data.bin2")
+	if err := ioutil.WriteFile(path, []byte("TODO: hello\x00\x01\x02"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	newGotcha := func(mode string) (*Gotcha, *bytes.Buffer) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		warn := bytes.NewBufferString("")
+		g.Warn.SetOutput(warn)
+		g.Binary = mode
+		return g, warn
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		g, warn := newGotcha("skip")
+		if res := g.gather(path); res.err != nil || len(res.matches) != 0 {
+			t.Errorf("expected skip but out=%#v", res)
+		}
+		if warn.Len() != 0 {
+			t.Errorf("unexpected warning: %s", warn)
+		}
+	})
+	t.Run("warn", func(t *testing.T) {
+		g, warn := newGotcha("warn")
+		if res := g.gather(path); res.err != nil || len(res.matches) != 0 {
+			t.Errorf("expected skip but out=%#v", res)
+		}
+		if !strings.Contains(warn.String(), path) {
+			t.Errorf("expected warning but out=%s", warn)
+		}
+	})
+	t.Run("scan", func(t *testing.T) {
+		g, _ := newGotcha("scan")
+		if res := g.gather(path); res.err != nil || len(res.matches) != 1 {
+			t.Errorf("expected a match but out=%#v", res)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package gotcha
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blame is attribution of a matched line by git blame
+type Blame struct {
+	Author string    `json:"author"`
+	Email  string    `json:"email"`
+	Commit string    `json:"commit"`
+	Date   time.Time `json:"date"`
+}
+
+func (b *Blame) String() string {
+	commit := b.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	return fmt.Sprintf("%s %s %s", b.Author, b.Date.Format("2006-01-02"), commit)
+}
+
+// blame attach Blame to each matches of gr with git blame --porcelain
+func (g *Gotcha) blame(gr *gatherRes) error {
+	if len(gr.matches) == 0 {
+		return nil
+	}
+	args := []string{"blame", "--porcelain"}
+	for _, m := range gr.matches {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", m.num, m.num))
+	}
+	args = append(args, "--", filepath.Base(gr.path))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = filepath.Dir(gr.path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git blame: %s: %v: %s", gr.path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	blames, err := parseBlamePorcelain(&stdout)
+	if err != nil {
+		return err
+	}
+	for _, m := range gr.matches {
+		m.blame = blames[m.num]
+	}
+	return nil
+}
+
+// parseBlamePorcelain return Blame per final line number
+func parseBlamePorcelain(r io.Reader) (map[uint]*Blame, error) {
+	var (
+		sc      = bufio.NewScanner(r)
+		blames  = make(map[uint]*Blame)
+		commits = make(map[string]*Blame)
+		cur     *Blame
+		line    uint
+	)
+	isCommit := func(s string) bool {
+		if len(s) != 40 {
+			return false
+		}
+		for _, c := range s {
+			if !strings.ContainsRune("0123456789abcdef", c) {
+				return false
+			}
+		}
+		return true
+	}
+	for sc.Scan() {
+		text := sc.Text()
+		// contents of the line, end of entry
+		if strings.HasPrefix(text, "\t") {
+			if cur != nil {
+				blames[line] = cur
+			}
+			cur = nil
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		key, value := fields[0], ""
+		if len(fields) == 2 {
+			value = fields[1]
+		}
+		switch {
+		case cur == nil && isCommit(key):
+			nums := strings.Fields(value)
+			if len(nums) < 2 {
+				return nil, fmt.Errorf("git blame: invalid header: %q", text)
+			}
+			n, err := strconv.ParseUint(nums[1], 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("git blame: invalid header: %q", text)
+			}
+			line = uint(n)
+			if cur = commits[key]; cur == nil {
+				cur = &Blame{Commit: key}
+				commits[key] = cur
+			}
+		case cur == nil:
+			return nil, fmt.Errorf("git blame: unexpected line: %q", text)
+		case key == "author":
+			cur.Author = value
+		case key == "author-mail":
+			cur.Email = strings.Trim(value, "<>")
+		case key == "author-time":
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("git blame: invalid author-time: %q", value)
+			}
+			cur.Date = time.Unix(sec, 0).UTC()
+		}
+	}
+	return blames, sc.Err()
+}
+
+// ParseAge parse duration of accepted suffix "d" as days and "w" as weeks
+// e.g. "90d", "2w", "36h"
+func ParseAge(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.ParseUint(s[:len(s)-1], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// olderThan drop matches of newer than g.OlderThan, matches without blame are dropped
+func (g *Gotcha) olderThan(gr *gatherRes) {
+	if g.OlderThan <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-g.OlderThan)
+	var matches []*match
+	for _, m := range gr.matches {
+		if m.blame != nil && m.blame.Date.Before(deadline) {
+			matches = append(matches, m)
+		}
+	}
+	gr.matches = matches
+}
+
+// Sorts available sort orders
+var Sorts = []string{"path", "age"}
+
+// sortResults sort held results by g.Sort
+// "age" split results into each matches and sort oldest first
+func (g *Gotcha) sortResults(results []*gatherRes) []*gatherRes {
+	switch g.Sort {
+	case "path":
+		sort.SliceStable(results, func(i, j int) bool { return results[i].path < results[j].path })
+	case "age":
+		var split []*gatherRes
+		for _, gr := range results {
+			for _, m := range gr.matches {
+				split = append(split, &gatherRes{path: gr.path, matches: []*match{m}})
+			}
+		}
+		date := func(gr *gatherRes) (time.Time, bool) {
+			b := gr.matches[0].blame
+			if b == nil {
+				return time.Time{}, false
+			}
+			return b.Date, true
+		}
+		sort.SliceStable(split, func(i, j int) bool {
+			di, oki := date(split[i])
+			dj, okj := date(split[j])
+			if oki != okj {
+				return oki
+			}
+			return di.Before(dj)
+		})
+		results = split
+	}
+	return results
+}
@@ -0,0 +1,158 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const porcelain = `1111111111111111111111111111111111111111 1 1 1
+author alice
+author-mail <alice@example.com>
+author-time 1500000000
+author-tz +0000
+summary first
+filename hello.go
+	// TODO: first
+2222222222222222222222222222222222222222 3 3 1
+author bob
+author-mail <bob@example.com>
+author-time 1600000000
+author-tz +0900
+summary second
+previous 1111111111111111111111111111111111111111 hello.go
+filename hello.go
+	// TODO: second
+1111111111111111111111111111111111111111 5 5 1
+	// TODO: third
+`
+
+func Test_parseBlamePorcelain(t *testing.T) {
+	alice := &Blame{
+		Author: "alice",
+		Email:  "alice@example.com",
+		Commit: strings.Repeat("1", 40),
+		Date:   time.Unix(1500000000, 0).UTC(),
+	}
+	bob := &Blame{
+		Author: "bob",
+		Email:  "bob@example.com",
+		Commit: strings.Repeat("2", 40),
+		Date:   time.Unix(1600000000, 0).UTC(),
+	}
+	exp := map[uint]*Blame{1: alice, 3: bob, 5: alice}
+
+	out, err := parseBlamePorcelain(strings.NewReader(porcelain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, out) {
+		t.Errorf("exp=%#v out=%#v", exp, out)
+	}
+
+	if _, err := parseBlamePorcelain(strings.NewReader("invalid\n")); err == nil {
+		t.Error("expected error but nil")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		exp     time.Duration
+		wanterr bool
+	}{
+		{in: "90d", exp: 90 * 24 * time.Hour},
+		{in: "2w", exp: 14 * 24 * time.Hour},
+		{in: "36h", exp: 36 * time.Hour},
+		{in: "xd", wanterr: true},
+		{in: "10", wanterr: true},
+	}
+	for _, test := range tests {
+		out, err := ParseAge(test.in)
+		if test.wanterr {
+			if err == nil {
+				t.Errorf("in=%q expected error but nil", test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("in=%q unexpected error: %v", test.in, err)
+		}
+		if test.exp != out {
+			t.Errorf("in=%q exp=%v out=%v", test.in, test.exp, out)
+		}
+	}
+}
+
+func TestSortByAge(t *testing.T) {
+	old := &match{num: 2, blame: &Blame{Date: time.Unix(100, 0)}}
+	young := &match{num: 1, blame: &Blame{Date: time.Unix(200, 0)}}
+	unknown := &match{num: 3}
+	older := &match{num: 9, blame: &Blame{Date: time.Unix(50, 0)}}
+
+	g := NewGotcha()
+	g.Sort = "age"
+	out := g.sortResults([]*gatherRes{
+		{path: "a", matches: []*match{young, old, unknown}},
+		{path: "b", matches: []*match{older}},
+	})
+	exp := []*gatherRes{
+		{path: "b", matches: []*match{older}},
+		{path: "a", matches: []*match{old}},
+		{path: "a", matches: []*match{young}},
+		{path: "a", matches: []*match{unknown}},
+	}
+	if !reflect.DeepEqual(exp, out) {
+		t.Errorf("exp=%#v out=%#v", exp, out)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root, err := filepath.Abs(filepath.Join(TestRoot, "blame"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "hello.txt")
+	if err := ioutil.WriteFile(path, []byte("TODO: old\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, root, "", "init", "-q")
+	gitRun(t, root, "2001-01-01T00:00:00Z", "add", "hello.txt")
+	gitRun(t, root, "2001-01-01T00:00:00Z", "commit", "-q", "-m", "old")
+	if err := ioutil.WriteFile(path, []byte("TODO: old\nTODO: new\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.Blame = true
+	res := g.gather(path)
+	if len(res.matches) != 2 {
+		t.Fatalf("unexpected matches: %#v", res.matches)
+	}
+	b := res.matches[0].blame
+	if b == nil || b.Author != "alice" || b.Email != "alice@example.com" || b.Date.Year() != 2001 {
+		t.Errorf("unexpected blame: %#v", b)
+	}
+
+	t.Run("older than", func(t *testing.T) {
+		g.OlderThan = 24 * time.Hour
+		res := g.gather(path)
+		if len(res.matches) != 1 || res.matches[0].num != 1 {
+			t.Errorf("expected only old match but out=%#v", res.matches)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package gotcha
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SGR sequences of text format, same colors as grep
+const (
+	sgrReset = "\x1b[0m"
+	sgrPath  = "\x1b[35m"
+	sgrLine  = "\x1b[32m"
+	sgrWord  = "\x1b[1;31m"
+	sgrSep   = "\x1b[36m"
+)
+
+// textStyle is decoration of text format
+type textStyle struct {
+	// highlight path, line number and the word by SGR
+	color bool
+	// OSC 8 hyperlinks of file URL
+	hyperlink bool
+	// "path:line:col: text" per match
+	editor bool
+}
+
+// textStyle return textStyle of g
+func (g *Gotcha) textStyle() textStyle {
+	return textStyle{color: g.Color, hyperlink: g.Hyperlink, editor: g.EditorFormat}
+}
+
+// paint wrap s by sgr if color
+func (st textStyle) paint(sgr, s string) string {
+	if !st.color || s == "" {
+		return s
+	}
+	return sgr + s + sgrReset
+}
+
+// link wrap s by OSC 8 hyperlink to line of path if hyperlink
+func (st textStyle) link(path string, line uint, s string) string {
+	if !st.hyperlink {
+		return s
+	}
+	u := fileURL(path, line)
+	if u == "" {
+		return s
+	}
+	return "\x1b]8;;" + u + "\x1b\\" + s + "\x1b]8;;\x1b\\"
+}
+
+// word highlight the word of m in the text at position of the match
+// not highlighted if the word is not in the text e.g. Trim
+func (st textStyle) word(m *match) string {
+	n := len(m.tag)
+	if m.wordLen != 0 {
+		n = m.wordLen
+	}
+	i := m.tagAt - 1
+	if !st.color || n == 0 || i < 0 || i+n > len(m.text) {
+		return m.text
+	}
+	return m.text[:i] + st.paint(sgrWord, m.text[i:i+n]) + m.text[i+n:]
+}
+
+// fileURL return file URL of path with fragment of the line, 0 is without fragment
+// members of archive are linked to the archive
+func fileURL(path string, line uint) string {
+	if i := strings.Index(path, ArchiveSep); i != -1 {
+		path, line = path[:i], 0
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		// e.g. "C:/path"
+		abs = "/" + abs
+	}
+	u := &url.URL{Scheme: "file", Path: abs}
+	if line != 0 {
+		u.Fragment = fmt.Sprintf("L%d", line)
+	}
+	return u.String()
+}
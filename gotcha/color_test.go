@@ -0,0 +1,83 @@
+package gotcha
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestFwriteStyle(t *testing.T) {
+	abs, err := filepath.Abs("path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr := &gatherRes{
+		path: "path",
+		matches: []*match{
+			{num: 2, col: 4, tag: "TODO: ", text: "// TODO: a", adds: []string{"3"}, tagAt: 4},
+			{num: 9, col: 1, tag: "TODO: ", text: "TODO: b", tagAt: 1},
+		},
+	}
+	tests := []struct {
+		st  textStyle
+		exp string
+	}{
+		{
+			st:  textStyle{color: true},
+			exp: "\x1b[35mpath\x1b[0m\n" + "\x1b[32mL2\x1b[0m:// \x1b[1;31mTODO: \x1b[0ma\n" + " \x1b[32m3\x1b[0m:3\n" + "\x1b[36m--\x1b[0m\n" + "\x1b[32mL9\x1b[0m:\x1b[1;31mTODO: \x1b[0mb\n\n",
+		},
+		{
+			st: textStyle{hyperlink: true},
+			exp: "\x1b]8;;file://" + filepath.ToSlash(abs) + "\x1b\\path\x1b]8;;\x1b\\\n" +
+				"\x1b]8;;file://" + filepath.ToSlash(abs) + "#L2\x1b\\L2\x1b]8;;\x1b\\:// TODO: a\n" + " 3:3\n" + "--\n" +
+				"\x1b]8;;file://" + filepath.ToSlash(abs) + "#L9\x1b\\L9\x1b]8;;\x1b\\:TODO: b\n\n",
+		},
+		{
+			st:  textStyle{editor: true},
+			exp: "path:2:4: // TODO: a\n" + "path-3-3\n" + "--\n" + "path:9:1: TODO: b\n",
+		},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := gr.fwrite(buf, test.st); err != nil {
+			t.Fatal(err)
+		}
+		if test.exp != buf.String() {
+			t.Errorf("style=%+v\nexp=%q\nout=%q", test.st, test.exp, buf.String())
+		}
+	}
+}
+
+func TestStyleWord(t *testing.T) {
+	st := textStyle{color: true}
+	tests := []struct {
+		m   *match
+		exp string
+	}{
+		// the word of the match, not first one e.g. in string literal of CommentsOnly
+		{
+			m:   &match{tag: "TODO: ", text: `s := "TODO: " // TODO: x`, tagAt: 18},
+			exp: `s := "TODO: " // ` + "\x1b[1;31mTODO: \x1b[0mx",
+		},
+		{
+			m:   &match{tag: "TODO: ", text: "// TODO(alice): x", tagAt: 4, wordLen: 13},
+			exp: "// \x1b[1;31mTODO(alice): \x1b[0mx",
+		},
+		// Trim
+		{m: &match{tag: "TODO: ", text: "x"}, exp: "x"},
+	}
+	for _, test := range tests {
+		if out := st.word(test.m); out != test.exp {
+			t.Errorf("exp=%q out=%q", test.exp, out)
+		}
+	}
+}
+
+func Test_fileURL(t *testing.T) {
+	if out := fileURL("/a b/c.go", 3); out != "file:///a%20b/c.go#L3" {
+		t.Errorf("unexpected url: %s", out)
+	}
+	if out := fileURL("/a.tar.gz!/src/x.go", 3); out != "file:///a.tar.gz" {
+		t.Errorf("unexpected url: %s", out)
+	}
+}
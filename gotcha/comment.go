@@ -0,0 +1,191 @@
+package gotcha
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// span is range of bytes in a line, end is exclusive
+type span struct {
+	start int
+	end   int
+}
+
+// spans is comment spans per line number, 1 origin
+type spans map[uint][]span
+
+// contains reports whether column index i of the line is in comments
+func (sp spans) contains(line uint, i int) bool {
+	for _, s := range sp[line] {
+		if s.start <= i && i < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+// add split a comment of started on line:col to lines
+func (sp spans) add(line uint, col int, comment []byte) {
+	for i, l := range bytes.Split(comment, []byte("\n")) {
+		if i != 0 {
+			col = 0
+		}
+		sp[line+uint(i)] = append(sp[line+uint(i)], span{start: col, end: col + len(l)})
+	}
+}
+
+// commentFinder return spans of comments in src
+type commentFinder func(src []byte) spans
+
+// CommentSyntax is lightweight lexer for comments, see CommentSyntaxes
+type CommentSyntax struct {
+	Line       []string // start of line comments
+	BlockStart string
+	BlockEnd   string
+	Quotes     string // quotes of string literal, skip comments in there
+}
+
+var (
+	cFamily = &CommentSyntax{
+		Line:       []string{"//"},
+		BlockStart: "/*",
+		BlockEnd:   "*/",
+		Quotes:     "\"'`",
+	}
+	hashFamily = &CommentSyntax{
+		Line:   []string{"#"},
+		Quotes: "\"'",
+	}
+	markupFamily = &CommentSyntax{
+		BlockStart: "<!--",
+		BlockEnd:   "-->",
+	}
+
+	// CommentSyntaxes map of extension or basename to comment syntax, Go is scanned by go/scanner
+	// other languages can be added
+	CommentSyntaxes = map[string]*CommentSyntax{
+		".c": cFamily, ".h": cFamily, ".cc": cFamily, ".cpp": cFamily, ".cxx": cFamily, ".hpp": cFamily,
+		".java": cFamily, ".kt": cFamily, ".scala": cFamily, ".cs": cFamily, ".swift": cFamily, ".rs": cFamily,
+		".js": cFamily, ".jsx": cFamily, ".ts": cFamily, ".tsx": cFamily, ".css": cFamily, ".proto": cFamily,
+		".php": cFamily,
+
+		".sh": hashFamily, ".bash": hashFamily, ".zsh": hashFamily, ".py": hashFamily, ".rb": hashFamily,
+		".pl": hashFamily, ".yml": hashFamily, ".yaml": hashFamily, ".toml": hashFamily, ".mk": hashFamily,
+		"Makefile": hashFamily, "Dockerfile": hashFamily,
+
+		".html": markupFamily, ".htm": markupFamily, ".xml": markupFamily, ".svg": markupFamily,
+		".md": markupFamily, ".markdown": markupFamily,
+	}
+)
+
+// commentFinderFor return commentFinder for the path
+// return nil if unknown language
+func commentFinderFor(path string) commentFinder {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext == ".go" {
+		return goComments
+	}
+	if cs, ok := CommentSyntaxes[ext]; ok {
+		return cs.find
+	}
+	if cs, ok := CommentSyntaxes[base]; ok {
+		return cs.find
+	}
+	return nil
+}
+
+// goComments use go/scanner
+func goComments(src []byte) spans {
+	sp := make(spans)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	// ignore syntax errors, comments are still scanned
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.COMMENT {
+			p := fset.Position(pos)
+			sp.add(uint(p.Line), p.Column-1, []byte(lit))
+		}
+	}
+	return sp
+}
+
+func (cs *CommentSyntax) find(src []byte) spans {
+	sp := make(spans)
+	var (
+		line      = uint(1)
+		lineStart int
+	)
+	hasPrefix := func(i int, prefix string) bool {
+		return prefix != "" && bytes.HasPrefix(src[i:], []byte(prefix))
+	}
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			lineStart = i + 1
+
+		case strings.IndexByte(cs.Quotes, c) != -1:
+			// skip string literal
+		str:
+			for i++; i < len(src) && src[i] != c; i++ {
+				switch src[i] {
+				case '\\':
+					if i++; i < len(src) && src[i] == '\n' {
+						line++
+						lineStart = i + 1
+					}
+				case '\n':
+					if c != '`' {
+						// unterminated
+						i--
+						break str
+					}
+					line++
+					lineStart = i + 1
+				}
+			}
+
+		case hasPrefix(i, cs.BlockStart):
+			end := bytes.Index(src[i+len(cs.BlockStart):], []byte(cs.BlockEnd))
+			if end == -1 {
+				end = len(src)
+			} else {
+				end += i + len(cs.BlockStart) + len(cs.BlockEnd)
+			}
+			comment := src[i:end]
+			sp.add(line, i-lineStart, comment)
+			if n := bytes.Count(comment, []byte("\n")); n != 0 {
+				line += uint(n)
+				lineStart = i + bytes.LastIndexByte(comment, '\n') + 1
+			}
+			i = end - 1
+
+		default:
+			for _, prefix := range cs.Line {
+				if hasPrefix(i, prefix) {
+					end := bytes.IndexByte(src[i:], '\n')
+					if end == -1 {
+						end = len(src)
+					} else {
+						end += i
+					}
+					sp.add(line, i-lineStart, src[i:end])
+					i = end - 1
+					break
+				}
+			}
+		}
+	}
+	return sp
+}
@@ -0,0 +1,112 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCommentFinder(t *testing.T) {
+	tests := []struct {
+		path string
+		src  string
+		exp  spans
+	}{
+		{
+			path: "main.go",
+			src:  "package main\n\nvar s = \"// TODO: \" // TODO: hi\n/* a\nb */\n",
+			exp: spans{
+				3: {{start: 20, end: 31}},
+				4: {{start: 0, end: 4}},
+				5: {{start: 0, end: 4}},
+			},
+		},
+		{
+			path: "main.c",
+			src:  "char *s = \"/* no */\"; /* TODO: */\n// TODO: c\n",
+			exp: spans{
+				1: {{start: 22, end: 33}},
+				2: {{start: 0, end: 10}},
+			},
+		},
+		{
+			path: "run.sh",
+			src:  "echo \"# no\" # TODO: sh\n",
+			exp: spans{
+				1: {{start: 12, end: 22}},
+			},
+		},
+		{
+			path: "index.html",
+			src:  "<p>TODO: no</p><!-- TODO:\nyes -->\n",
+			exp: spans{
+				1: {{start: 15, end: 25}},
+				2: {{start: 0, end: 7}},
+			},
+		},
+		{
+			path: "Makefile",
+			src:  "all: # TODO: make\n",
+			exp: spans{
+				1: {{start: 5, end: 17}},
+			},
+		},
+	}
+	for _, test := range tests {
+		find := commentFinderFor(test.path)
+		if find == nil {
+			t.Fatalf("expected finder for %s but nil", test.path)
+		}
+		out := find([]byte(test.src))
+		if !reflect.DeepEqual(test.exp, out) {
+			t.Errorf("path=%s exp=%#v out=%#v", test.path, test.exp, out)
+		}
+	}
+
+	if find := commentFinderFor("unknown.txt"); find != nil {
+		t.Error("expected nil finder for unknown type")
+	}
+}
+
+func TestCommentsOnly(t *testing.T) {
+	root := filepath.Join(TestRoot, "comments_only")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "main.go")
+	src := "package main\n\nvar s = \"TODO: \" // TODO: hi\nvar t = \"TODO: \"\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.CommentsOnly = true
+	res := g.gather(path)
+	exp := &gatherRes{
+		path: path,
+		matches: []*match{
+			{num: 3, col: 21, tagAt: 21, tag: "TODO: ", text: "var s = \"TODO: \" // TODO: hi"},
+		},
+	}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("exp=%#v out=%#v", exp, res)
+	}
+}
+
+func TestCommentSyntaxes(t *testing.T) {
+	CommentSyntaxes[".lua"] = &CommentSyntax{Line: []string{"--"}, BlockStart: "--[[", BlockEnd: "]]", Quotes: "\"'"}
+	defer delete(CommentSyntaxes, ".lua")
+	find := commentFinderFor("a.lua")
+	if find == nil {
+		t.Fatal("added syntax is not used")
+	}
+	sp := find([]byte("s = \"-- TODO: no\" -- TODO: yes\n"))
+	if sp.contains(1, 7) || !sp.contains(1, 21) {
+		t.Errorf("unexpected spans: %v", sp)
+	}
+}
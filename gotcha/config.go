@@ -0,0 +1,107 @@
+package gotcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ConfigName is basename of project configuration
+const ConfigName = ".gotcha"
+
+// Config is project configuration of ".gotcha" in JSON
+// lists of ignores are appended to defaults, others are overwritten
+type Config struct {
+	Words       []string `json:"words"`
+	Types       []string `json:"types"`
+	IgnoreDirs  []string `json:"ignore_dirs"`
+	IgnoreBases []string `json:"ignore_bases"`
+	IgnoreTypes []string `json:"ignore_types"`
+	Max         *int     `json:"max"`
+	Add         *uint    `json:"add"`
+	// encoding of files and by extension
+	Encoding  string            `json:"encoding"`
+	Encodings map[string]string `json:"encodings"`
+	// patterns of Annotation, overwrite DefaultAnnotations
+	Annotations []string `json:"annotations"`
+}
+
+// ReadConfig read Config from file
+func ReadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := &Config{}
+	if err := json.Unmarshal(b, conf); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return conf, nil
+}
+
+// merge overwrite conf by c
+func (conf *Config) merge(c *Config) {
+	if len(c.Words) != 0 {
+		conf.Words = c.Words
+	}
+	if len(c.Types) != 0 {
+		conf.Types = c.Types
+	}
+	conf.IgnoreDirs = append(conf.IgnoreDirs, c.IgnoreDirs...)
+	conf.IgnoreBases = append(conf.IgnoreBases, c.IgnoreBases...)
+	conf.IgnoreTypes = append(conf.IgnoreTypes, c.IgnoreTypes...)
+	if c.Max != nil {
+		conf.Max = c.Max
+	}
+	if c.Add != nil {
+		conf.Add = c.Add
+	}
+	if c.Encoding != "" {
+		conf.Encoding = c.Encoding
+	}
+	if len(c.Annotations) != 0 {
+		conf.Annotations = c.Annotations
+	}
+	for ext, enc := range c.Encodings {
+		if conf.Encodings == nil {
+			conf.Encodings = make(map[string]string)
+		}
+		conf.Encodings[ext] = enc
+	}
+}
+
+// LoadConfig read ConfigName from root and parents of root
+// nearer configuration has priority, return nil if not found
+func LoadConfig(root string) (*Config, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		abs = filepath.Dir(abs)
+	}
+	var paths []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, ConfigName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	conf := &Config{}
+	for i := len(paths) - 1; i >= 0; i-- {
+		c, err := ReadConfig(paths[i])
+		if err != nil {
+			return nil, err
+		}
+		conf.merge(c)
+	}
+	return conf, nil
+}
@@ -0,0 +1,48 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	root := filepath.Join(TestRoot, "load_config")
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(path, contents string) {
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, ConfigName), `{"words": ["TODO: "], "ignore_dirs": ["vendor"], "max": 100}`)
+	write(filepath.Join(sub, ConfigName), `{"words": ["FIXME: ", "BUG: "], "ignore_dirs": ["testdata"], "add": 2}`)
+
+	conf, err := LoadConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	max, add := 100, uint(2)
+	exp := &Config{
+		Words:      []string{"FIXME: ", "BUG: "},
+		IgnoreDirs: []string{"vendor", "testdata"},
+		Max:        &max,
+		Add:        &add,
+	}
+	if !reflect.DeepEqual(exp, conf) {
+		t.Errorf("exp=%#v out=%#v", exp, conf)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		write(filepath.Join(sub, ConfigName), `{invalid`)
+		if _, err := LoadConfig(sub); err == nil {
+			t.Error("expected error but nil")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package gotcha
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveSep separate path of archive and member in virtual paths
+// e.g. "a.tar.gz!/src/x.go"
+const ArchiveSep = "!/"
+
+// decompressor return reader of decompressed stream
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// streams are decompressors by extension, inner is extension of after decompress
+var streams = map[string]struct {
+	dec   decompressor
+	inner string
+}{
+	".gz":  {dec: gzipReader},
+	".bz2": {dec: bzip2Reader},
+	".xz":  {dec: xzReader},
+	".tgz": {dec: gzipReader, inner: ".tar"},
+	".tbz": {dec: bzip2Reader, inner: ".tar"},
+	".txz": {dec: xzReader, inner: ".tar"},
+}
+
+func gzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func bzip2Reader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bzip2.NewReader(r)), nil
+}
+
+// xzReader decompress by xz command, xz is not in standard library
+func xzReader(r io.Reader) (io.ReadCloser, error) {
+	return commandReader(r, "xz", "--decompress", "--stdout")
+}
+
+// commandReader return stdout of command of filter r, Close wait the command
+func commandReader(r io.Reader, name string, args ...string) (io.ReadCloser, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = r
+	cmd.Stderr = new(bytes.Buffer)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return &cmdReader{ReadCloser: out, cmd: cmd}, nil
+}
+
+// cmdReader is stdout of cmd, Close wait the cmd
+type cmdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (cr *cmdReader) Close() error {
+	cr.ReadCloser.Close()
+	if err := cr.cmd.Wait(); err != nil {
+		return fmt.Errorf("%s: %v: %s", filepath.Base(cr.cmd.Path), err, strings.TrimSpace(cr.cmd.Stderr.(*bytes.Buffer).String()))
+	}
+	return nil
+}
+
+// isCompressed reports whether name is compressed file or archive by extension
+func isCompressed(name string) bool {
+	ext := filepath.Ext(name)
+	_, ok := streams[ext]
+	return ok || ext == ".tar" || ext == ".zip"
+}
+
+// gatherFile gather file of path
+// if Decompress then compressed files are decompressed and members of
+// archives are gathered with virtual path, inner files and members are filtered as files of walk
+func (g *Gotcha) gatherFile(path string) []*gatherRes {
+	if !g.Decompress || !isCompressed(path) {
+		return []*gatherRes{g.gather(path)}
+	}
+	fail := func(err error) []*gatherRes {
+		return []*gatherRes{{path: path, err: fmt.Errorf("%s: %v", path, err)}}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return []*gatherRes{{path: path, err: err}}
+	}
+	defer f.Close()
+
+	var (
+		r    io.Reader = f
+		name           = filepath.Base(path)
+		ext            = filepath.Ext(name)
+	)
+	if s, ok := streams[ext]; ok {
+		rc, err := s.dec(f)
+		if err != nil {
+			return fail(err)
+		}
+		defer rc.Close()
+		r, name = rc, strings.TrimSuffix(name, ext)+s.inner
+		ext = filepath.Ext(name)
+		// filter inner file as members
+		if ext != ".tar" && !g.isTarget(name) {
+			g.Log.Printf("ignored: [%v]\n\n", path)
+			return []*gatherRes{{path: path}}
+		}
+	}
+
+	switch {
+	case ext == ".tar":
+		return g.gatherTar(path, r)
+	case ext == ".zip" && r == f:
+		info, err := f.Stat()
+		if err != nil {
+			return fail(err)
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return fail(err)
+		}
+		return g.gatherZip(path, zr)
+	default:
+		return []*gatherRes{g.gatherReader(path, name, r)}
+	}
+}
+
+// memberPath return virtual path of member of archive
+func memberPath(archive, name string) string {
+	return archive + ArchiveSep + strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (g *Gotcha) gatherTar(archive string, r io.Reader) []*gatherRes {
+	var (
+		grs []*gatherRes
+		tr  = tar.NewReader(r)
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return grs
+		}
+		if err != nil {
+			return append(grs, &gatherRes{path: archive, err: fmt.Errorf("%s: %v", archive, err)})
+		}
+		if hdr.Typeflag != tar.TypeReg || !g.isTarget(path.Base(hdr.Name)) {
+			continue
+		}
+		vpath := memberPath(archive, hdr.Name)
+		grs = append(grs, g.gatherReader(vpath, vpath, tr))
+	}
+}
+
+func (g *Gotcha) gatherZip(archive string, zr *zip.Reader) []*gatherRes {
+	var grs []*gatherRes
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || !g.isTarget(path.Base(f.Name)) {
+			continue
+		}
+		vpath := memberPath(archive, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			grs = append(grs, &gatherRes{path: vpath, err: fmt.Errorf("%s: %v", vpath, err)})
+			continue
+		}
+		grs = append(grs, g.gatherReader(vpath, vpath, rc))
+		rc.Close()
+	}
+	return grs
+}
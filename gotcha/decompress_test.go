@@ -0,0 +1,148 @@
+package gotcha
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecompress(t *testing.T) {
+	root := filepath.Join(TestRoot, "decompress")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(name string, b []byte) string {
+		path := filepath.Join(root, name)
+		if err := ioutil.WriteFile(path, b, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	gz := func(b []byte) []byte {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		zw.Write(b)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	tarball := func(files map[string]string, names ...string) []byte {
+		buf := new(bytes.Buffer)
+		tw := tar.NewWriter(buf)
+		for _, name := range names {
+			hdr := &tar.Header{Name: name, Mode: 0666, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Write([]byte(files[name]))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	gzPath := write("a.txt.gz", gz([]byte("TODO: gzip\n")))
+	// filtered by inner name
+	write("a.log.gz", gz([]byte("TODO: ignored type\n")))
+	write("a.png.gz", gz([]byte("TODO: ignored type\n")))
+	tgzPath := write("b.tar.gz", gz(tarball(map[string]string{
+		"src/x.go":  "// TODO: tar\n",
+		"image.png": "TODO: ignored type",
+	}, "src/x.go", "image.png")))
+	zbuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zbuf)
+	for _, name := range []string{"dir/", "dir/y.txt"} {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "dir/y.txt" {
+			f.Write([]byte("none\nTODO: zip\n"))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := write("c.zip", zbuf.Bytes())
+	brokenPath := write("d.gz", []byte("not gzip"))
+
+	exp := gzPath + "\nL1:TODO: gzip\n\n" +
+		tgzPath + "!/src/x.go\nL1:// TODO: tar\n\n" +
+		zipPath + "!/dir/y.txt\nL2:TODO: zip\n\n"
+
+	for _, work := range []string{"async", "sync"} {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		buf := new(bytes.Buffer)
+		g.W = buf
+		g.Decompress = true
+		var res *Result
+		if work == "async" {
+			res = g.WorkGo(context.Background(), root, 0)
+		} else {
+			res = g.SyncWorkGo(context.Background(), root)
+		}
+		if exp != buf.String() {
+			t.Errorf("%s: exp=%#v out=%#v", work, exp, buf.String())
+		}
+		if len(res.Errors) != 1 {
+			t.Errorf("%s: expected error of %s but %v", work, brokenPath, res.Errors)
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		buf := new(bytes.Buffer)
+		g.W = buf
+		if res := g.WorkGo(context.Background(), root, 0); len(res.Errors) != 0 || buf.Len() != 0 {
+			t.Errorf("expected skip archives but errors=%v out=%q", res.Errors, buf)
+		}
+	})
+
+	// bzip2 and xz have no writer in standard library
+	for _, c := range []struct{ cmd, ext string }{{"bzip2", ".bz2"}, {"xz", ".xz"}} {
+		t.Run(c.cmd, func(t *testing.T) {
+			if _, err := exec.LookPath(c.cmd); err != nil {
+				t.Skip(err)
+			}
+			dir := filepath.Join(root, c.cmd)
+			if err := os.MkdirAll(dir, 0777); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "e.tar")
+			if err := ioutil.WriteFile(path, tarball(map[string]string{"e.txt": "TODO: " + c.cmd}, "e.txt"), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(c.cmd, path).CombinedOutput(); err != nil {
+				t.Fatalf("%v: %s", err, out)
+			}
+
+			g := NewGotcha()
+			g.Log.SetOutput(ioutil.Discard)
+			g.Decompress = true
+			var out []*Match
+			res := g.Search(context.Background(), dir, func(m *Match) error {
+				out = append(out, m)
+				return nil
+			})
+			if len(res.Errors) != 0 {
+				t.Fatal(res.Errors)
+			}
+			if len(out) != 1 || out[0].Path != path+c.ext+ArchiveSep+"e.txt" || out[0].Text != "TODO: "+c.cmd {
+				t.Errorf("unexpected matches: %#v", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package gotcha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// encodings of built in, others are transcoded by iconv command
+const (
+	EncodingAuto    = "auto"
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16   = "utf-16"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+)
+
+// encodingOf return encoding for file of name by EncodingsMap and Encoding
+func (g *Gotcha) encodingOf(name string) string {
+	if enc, ok := g.EncodingsMap[filepath.Ext(name)]; ok {
+		return strings.ToLower(enc)
+	}
+	if g.Encoding == "" {
+		return EncodingAuto
+	}
+	return strings.ToLower(g.Encoding)
+}
+
+// decode return reader of UTF-8 from br of encoding enc
+// BOM is dropped, "auto" detect BOM and common encodings by head of br
+// close must be called after read, it report error of transcode
+func (g *Gotcha) decode(path string, br *bufio.Reader, enc string) (r io.Reader, close func() error, err error) {
+	nop := func() error { return nil }
+	sample, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, err
+	}
+	auto := enc == EncodingAuto
+	var bom int
+	if auto {
+		enc, bom = detectEncoding(sample)
+	} else if e, n := detectBOM(sample); n != 0 && (e == enc || enc == EncodingUTF16 && e != EncodingUTF8) {
+		enc, bom = e, n
+	}
+	br.Discard(bom)
+
+	switch enc {
+	case "", EncodingUTF8:
+		return br, nop, nil
+	case EncodingUTF16:
+		// without BOM
+		if enc = detectUTF16(sample); enc == "" {
+			enc = EncodingUTF16BE
+		}
+		fallthrough
+	case EncodingUTF16LE, EncodingUTF16BE:
+		var order binary.ByteOrder = binary.BigEndian
+		if enc == EncodingUTF16LE {
+			order = binary.LittleEndian
+		}
+		return &utf16Reader{r: br, order: order}, nop, nil
+	}
+	if _, err := exec.LookPath("iconv"); err != nil && auto {
+		g.Log.Printf("can not transcode %s of detected: [%v]: %v\n\n", enc, path, err)
+		return br, nop, nil
+	}
+	rc, err := commandReader(br, "iconv", "-f", enc, "-t", "UTF-8")
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, rc.Close, nil
+}
+
+// detectBOM return encoding and length of BOM of head of b
+func detectBOM(b []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		return EncodingUTF8, 3
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		return EncodingUTF16LE, 2
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		return EncodingUTF16BE, 2
+	}
+	return "", 0
+}
+
+// detectEncoding guess encoding of sample and length of BOM
+// return "" for UTF-8 or unknown
+func detectEncoding(sample []byte) (string, int) {
+	if enc, n := detectBOM(sample); n != 0 {
+		return enc, n
+	}
+	if enc := detectUTF16(sample); enc != "" {
+		return enc, 0
+	}
+	if validUTF8(sample) || bytes.IndexByte(sample, 0) != -1 {
+		return "", 0
+	}
+	// lead bytes of Shift_JIS for kana are invalid in EUC-JP
+	switch {
+	case validEUCJP(sample):
+		return "euc-jp", 0
+	case validShiftJIS(sample):
+		return "shift_jis", 0
+	}
+	return "", 0
+}
+
+// detectUTF16 guess UTF-16 without BOM by NUL of high bytes of ASCII
+func detectUTF16(b []byte) string {
+	n := len(b) / 2
+	if n < 4 {
+		return ""
+	}
+	var even, odd int
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			// NUL is not text
+			return ""
+		}
+		if b[i] == 0 {
+			even++
+		}
+		if b[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd*10 > n*4 && even*10 < n:
+		return EncodingUTF16LE
+	case even*10 > n*4 && odd*10 < n:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// validUTF8 reports whether b is UTF-8, b may be cut in a rune at end
+func validUTF8(b []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(b) != 0; i++ {
+		if utf8.Valid(b) {
+			return true
+		}
+		b = b[:len(b)-1]
+	}
+	return false
+}
+
+func validEUCJP(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c < 0x80:
+		case c == 0x8e && i+1 < len(b):
+			// half width katakana
+			if i++; b[i] < 0xa1 || b[i] > 0xdf {
+				return false
+			}
+		case c == 0x8f && i+2 < len(b):
+			// JIS X 0212
+			for j := 0; j != 2; j++ {
+				if i++; b[i] < 0xa1 || b[i] == 0xff {
+					return false
+				}
+			}
+		case c >= 0xa1 && c <= 0xfe && i+1 < len(b):
+			if i++; b[i] < 0xa1 || b[i] == 0xff {
+				return false
+			}
+		case i+2 >= len(b) && c >= 0x8e:
+			// cut at end
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validShiftJIS(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c < 0x80, c >= 0xa1 && c <= 0xdf:
+			// ASCII and half width katakana
+		case (c >= 0x81 && c <= 0x9f || c >= 0xe0 && c <= 0xfc) && i+1 < len(b):
+			if i++; b[i] < 0x40 || b[i] == 0x7f || b[i] > 0xfc {
+				return false
+			}
+		case i+1 == len(b) && c >= 0x81:
+			// cut at end
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// utf16Reader transcode UTF-16 of r to UTF-8
+type utf16Reader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	out   []byte // pending of UTF-8
+}
+
+// next return next rune
+func (u *utf16Reader) next() (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return utf8.RuneError, nil
+		}
+		return 0, err
+	}
+	r := rune(u.order.Uint16(b[:]))
+	if r < 0xd800 || r > 0xdbff {
+		// not high surrogate
+		if utf16.IsSurrogate(r) {
+			return utf8.RuneError, nil
+		}
+		return r, nil
+	}
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		return utf8.RuneError, nil
+	}
+	return utf16.DecodeRune(r, rune(u.order.Uint16(b[:]))), nil
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.out) == 0 {
+			r, err := u.next()
+			if err != nil {
+				if n != 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+			u.out = utf8.AppendRune(u.out[:0], r)
+		}
+		c := copy(p[n:], u.out)
+		u.out = u.out[c:]
+		n += c
+	}
+	return n, nil
+}
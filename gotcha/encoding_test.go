@@ -0,0 +1,120 @@
+package gotcha
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// "x 課題: テスト\n"
+var (
+	shiftJISText = []byte{0x78, 0x20, 0x89, 0xdb, 0x91, 0xe8, 0x3a, 0x20, 0x83, 0x65, 0x83, 0x58, 0x83, 0x67, 0x0a}
+	eucJPText    = []byte{0x78, 0x20, 0xb2, 0xdd, 0xc2, 0xea, 0x3a, 0x20, 0xa5, 0xc6, 0xa5, 0xb9, 0xa5, 0xc8, 0x0a}
+)
+
+func encodeUTF16(s string, bigEndian, bom bool) []byte {
+	var b []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func Test_detectEncoding(t *testing.T) {
+	tests := []struct {
+		in  []byte
+		enc string
+		bom int
+	}{
+		{in: []byte("TODO: hello\n"), enc: ""},
+		{in: []byte("\xef\xbb\xbfTODO: hello\n"), enc: EncodingUTF8, bom: 3},
+		{in: encodeUTF16("TODO: hello\n", false, true), enc: EncodingUTF16LE, bom: 2},
+		{in: encodeUTF16("TODO: hello\n", true, true), enc: EncodingUTF16BE, bom: 2},
+		{in: encodeUTF16("TODO: hello\n", false, false), enc: EncodingUTF16LE},
+		{in: encodeUTF16("TODO: hello\n", true, false), enc: EncodingUTF16BE},
+		{in: []byte("x 課題: テスト\n"), enc: ""},
+		{in: shiftJISText, enc: "shift_jis"},
+		{in: eucJPText, enc: "euc-jp"},
+		{in: []byte{0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x10, 0x00, 0x7f}, enc: ""},
+	}
+	for _, test := range tests {
+		enc, bom := detectEncoding(test.in)
+		if enc != test.enc || bom != test.bom {
+			t.Errorf("in=%q exp=%q,%d out=%q,%d", test.in, test.enc, test.bom, enc, bom)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	root := filepath.Join(TestRoot, "encoding")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name     string
+		contents []byte
+		iconv    bool
+	}{
+		{name: "utf8.txt", contents: []byte("\xef\xbb\xbfx 課題: テスト\n")},
+		{name: "utf16le.txt", contents: encodeUTF16("x 課題: テスト\r\n", false, true)},
+		{name: "utf16be.txt", contents: encodeUTF16("x 課題: テスト\n", true, false)},
+		{name: "sjis.txt", contents: shiftJISText, iconv: true},
+		{name: "eucjp.txt", contents: eucJPText, iconv: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := exec.LookPath("iconv"); test.iconv && err != nil {
+				t.Skip(err)
+			}
+			path := filepath.Join(root, test.name)
+			if err := ioutil.WriteFile(path, test.contents, 0666); err != nil {
+				t.Fatal(err)
+			}
+			g := NewGotcha()
+			g.Log.SetOutput(ioutil.Discard)
+			g.Words = []string{"課題: "}
+			exp := &gatherRes{
+				path:    path,
+				matches: []*match{{num: 1, col: 3, tagAt: 3, tag: "課題: ", text: "x 課題: テスト"}},
+			}
+			if out := g.gather(path); !reflect.DeepEqual(exp, out) {
+				t.Errorf("exp=%#v out=%#v", exp, out)
+			}
+		})
+	}
+
+	t.Run("by extension", func(t *testing.T) {
+		if _, err := exec.LookPath("iconv"); err != nil {
+			t.Skip(err)
+		}
+		path := filepath.Join(root, "legacy.sjis")
+		if err := ioutil.WriteFile(path, shiftJISText, 0666); err != nil {
+			t.Fatal(err)
+		}
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.Words = []string{"課題: "}
+		g.Encoding = EncodingUTF8
+		if gr := g.gather(path); len(gr.matches) != 0 {
+			t.Errorf("expected no matches as UTF-8 but %#v", gr.matches)
+		}
+		g.EncodingsMap[".sjis"] = "SHIFT_JIS"
+		if gr := g.gather(path); gr.err != nil || len(gr.matches) != 1 {
+			t.Errorf("expected a match but err=%v matches=%#v", gr.err, gr.matches)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package gotcha
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func ExampleGotcha_Search() {
+	root := filepath.Join("t", "example")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(root)
+	src := "package main\n\n// TODO: hello\nfunc main() {} // FIXME: world\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "main.go"), []byte(src), 0666); err != nil {
+		panic(err)
+	}
+
+	g := NewGotcha()
+	g.Words = []string{"TODO: ", "FIXME: "}
+	res := g.Search(context.Background(), root, func(m *Match) error {
+		fmt.Printf("%s:%d:%d: %s\n", filepath.ToSlash(m.Path), m.Line, m.Col, m.Text)
+		return nil
+	})
+	fmt.Println("files", res.Files, "lines", res.Lines)
+	// Output:
+	// t/example/main.go:3:4: // TODO: hello
+	// t/example/main.go:4:19: func main() {} // FIXME: world
+	// files 1 lines 2
+}
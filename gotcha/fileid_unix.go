@@ -0,0 +1,18 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package gotcha
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIDOf return device and inode of info
+func fileIDOf(path string, info os.FileInfo) fileID {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{path: path}
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+}
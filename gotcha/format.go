@@ -0,0 +1,292 @@
+package gotcha
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats available output formats
+var Formats = []string{"text", "json", "jsonl", "csv", "sarif", "html"}
+
+// recordWriter write gathered results with format
+type recordWriter interface {
+	// write a result
+	write(gr *gatherRes) error
+	// group notify start of the tag group
+	group(tag string) error
+	// flush write buffered results
+	flush() error
+}
+
+func newRecordWriter(format string, w io.Writer) (recordWriter, error) {
+	switch format {
+	case "", "text":
+		return &textWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonWriter{w: w, lines: true}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "sarif":
+		return &sarifWriter{w: w}, nil
+	case "html":
+		return &htmlWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// textWriter is default format
+type textWriter struct {
+	w     io.Writer
+	style textStyle
+}
+
+func (tw *textWriter) write(gr *gatherRes) error {
+	return gr.fwrite(tw.w, tw.style)
+}
+
+func (tw *textWriter) group(tag string) error {
+	_, err := fmt.Fprintf(tw.w, "[%s]\n\n", tag)
+	return err
+}
+
+func (tw *textWriter) flush() error { return nil }
+
+// jsonWriter write json array on flush or json lines on each write
+type jsonWriter struct {
+	w       io.Writer
+	lines   bool
+	records []*Match
+}
+
+func (jw *jsonWriter) write(gr *gatherRes) error {
+	if !jw.lines {
+		jw.records = append(jw.records, gr.toMatches()...)
+		return nil
+	}
+	enc := json.NewEncoder(jw.w)
+	for _, r := range gr.toMatches() {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jw *jsonWriter) group(tag string) error { return nil }
+
+func (jw *jsonWriter) flush() error {
+	if jw.lines {
+		return nil
+	}
+	if jw.records == nil {
+		jw.records = []*Match{}
+	}
+	b, err := json.MarshalIndent(jw.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(jw.w, string(b))
+	return err
+}
+
+// csvWriter write header with first record or on flush if empty, lines of context are joined by newline
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (cw *csvWriter) write(gr *gatherRes) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	for _, r := range gr.toMatches() {
+		var author, email, commit, date string
+		if r.Blame != nil {
+			author, email, commit = r.Blame.Author, r.Blame.Email, r.Blame.Commit
+			date = r.Blame.Date.Format(time.RFC3339)
+		}
+		var owner, due, issue string
+		if r.Annotation != nil {
+			owner, due, issue = r.Annotation.Owner, r.Annotation.Due, r.Annotation.Issue
+		}
+		err := cw.w.Write([]string{
+			r.Path,
+			strconv.FormatUint(uint64(r.Line), 10),
+			strconv.Itoa(r.Col),
+			r.Tag,
+			r.Text,
+			strings.Join(r.Before, "\n"),
+			strings.Join(r.Context, "\n"),
+			author,
+			email,
+			commit,
+			date,
+			owner,
+			due,
+			issue,
+			r.Symbol,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cw *csvWriter) group(tag string) error { return nil }
+
+// writeHeader write header once
+func (cw *csvWriter) writeHeader() error {
+	if cw.header {
+		return nil
+	}
+	cw.header = true
+	return cw.w.Write([]string{"path", "line", "column", "tag", "text", "before", "context", "author", "email", "commit", "date", "owner", "due", "issue", "symbol"})
+}
+
+func (cw *csvWriter) flush() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// sarifWriter write SARIF 2.1.0 log on flush
+// rule id is the tag of trimmed spaces and colons
+type sarifWriter struct {
+	w       io.Writer
+	records []*Match
+}
+
+func (sw *sarifWriter) write(gr *gatherRes) error {
+	sw.records = append(sw.records, gr.toMatches()...)
+	return nil
+}
+
+func (sw *sarifWriter) group(tag string) error { return nil }
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver sarifDriver `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+	// columns are counted in runes
+	ColumnKind string `json:"columnKind"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties *Annotation     `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   uint         `json:"startLine"`
+			StartColumn int          `json:"startColumn"`
+			Snippet     sarifMessage `json:"snippet"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func sarifRuleID(tag string) string {
+	id := strings.Trim(tag, " \t:")
+	if id == "" {
+		return tag
+	}
+	return id
+}
+
+func (sw *sarifWriter) flush() error {
+	run := sarifRun{Results: []sarifResult{}, ColumnKind: "unicodeCodePoints"}
+	run.Tool.Driver = sarifDriver{
+		Name:           Name,
+		Version:        Version,
+		InformationURI: "https://github.com/yaeshimo/go-utils",
+		Rules:          []sarifRule{},
+	}
+	rules := make(map[string]bool)
+	for _, r := range sw.records {
+		id := sarifRuleID(r.Tag)
+		if !rules[id] {
+			rules[id] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               id,
+				ShortDescription: sarifMessage{Text: strconv.Quote(r.Tag) + " comment"},
+			})
+		}
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(r.Path)
+		loc.PhysicalLocation.Region.StartLine = r.Line
+		loc.PhysicalLocation.Region.StartColumn = r.Col
+		loc.PhysicalLocation.Region.Snippet.Text = r.Text
+		if r.Symbol != "" {
+			kind := "function"
+			if strings.HasPrefix(r.Symbol, "type ") || strings.HasPrefix(r.Symbol, "class ") {
+				kind = "type"
+			}
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: r.Symbol, Kind: kind}}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:     id,
+			Level:      "note",
+			Message:    sarifMessage{Text: strings.TrimSpace(r.Text)},
+			Locations:  []sarifLocation{loc},
+			Properties: r.Annotation,
+		})
+	}
+	b, err := json.MarshalIndent(&sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(sw.w, string(b))
+	return err
+}
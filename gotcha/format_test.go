@@ -0,0 +1,169 @@
+package gotcha
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordWriter(t *testing.T) {
+	results := []*gatherRes{
+		{
+			path: "a.go",
+			matches: []*match{
+				{num: 3, col: 4, tag: "TODO: ", text: "// TODO: hello", adds: []string{"next"}, symbol: "func main"},
+			},
+		},
+		{
+			path: "b.go",
+			matches: []*match{
+				{num: 1, col: 1, tag: "FIXME: ", text: "FIXME: (alice, 2001-02-03) world", annotation: &Annotation{Owner: "alice", Due: "2001-02-03"}},
+			},
+		},
+	}
+	exp := []*Match{
+		{Path: "a.go", Line: 3, Col: 4, Tag: "TODO: ", Text: "// TODO: hello", Context: []string{"next"}, Symbol: "func main"},
+		{Path: "b.go", Line: 1, Col: 1, Tag: "FIXME: ", Text: "FIXME: (alice, 2001-02-03) world", Annotation: &Annotation{Owner: "alice", Due: "2001-02-03"}},
+	}
+	writeAll := func(t *testing.T, format string) *bytes.Buffer {
+		buf := bytes.NewBufferString("")
+		rw, err := newRecordWriter(format, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, gr := range results {
+			if err := rw.write(gr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var out []*Match
+		if err := json.Unmarshal(writeAll(t, "json").Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(exp, out) {
+			t.Errorf("exp=%#v out=%#v", exp, out)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		dec := json.NewDecoder(writeAll(t, "jsonl"))
+		var out []*Match
+		for dec.More() {
+			r := new(Match)
+			if err := dec.Decode(r); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, r)
+		}
+		if !reflect.DeepEqual(exp, out) {
+			t.Errorf("exp=%#v out=%#v", exp, out)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		out, err := csv.NewReader(writeAll(t, "csv")).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expcsv := [][]string{
+			{"path", "line", "column", "tag", "text", "before", "context", "author", "email", "commit", "date", "owner", "due", "issue", "symbol"},
+			{"a.go", "3", "4", "TODO: ", "// TODO: hello", "", "next", "", "", "", "", "", "", "", "func main"},
+			{"b.go", "1", "1", "FIXME: ", "FIXME: (alice, 2001-02-03) world", "", "", "", "", "", "", "alice", "2001-02-03", "", ""},
+		}
+		if !reflect.DeepEqual(expcsv, out) {
+			t.Errorf("exp=%#v out=%#v", expcsv, out)
+		}
+	})
+
+	t.Run("empty csv", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		rw, err := newRecordWriter("csv", buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		out, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 || out[0][0] != "path" {
+			t.Errorf("expected header only: %q", out)
+		}
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var out sarifLog
+		if err := json.Unmarshal(writeAll(t, "sarif").Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Version != "2.1.0" || len(out.Runs) != 1 || out.Runs[0].ColumnKind != "unicodeCodePoints" {
+			t.Fatalf("unexpected log: %#v", out)
+		}
+		run := out.Runs[0]
+		if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "TODO" {
+			t.Errorf("unexpected rules: %#v", run.Tool.Driver.Rules)
+		}
+		if len(run.Results) != 2 {
+			t.Fatalf("unexpected results: %#v", run.Results)
+		}
+		region := run.Results[0].Locations[0].PhysicalLocation.Region
+		if region.StartLine != 3 || region.StartColumn != 4 {
+			t.Errorf("unexpected region: %#v", region)
+		}
+		if ll := run.Results[0].Locations[0].LogicalLocations; len(ll) != 1 || ll[0].FullyQualifiedName != "func main" || ll[0].Kind != "function" {
+			t.Errorf("unexpected logical locations: %#v", ll)
+		}
+		if p := run.Results[1].Properties; p == nil || p.Owner != "alice" {
+			t.Errorf("unexpected properties: %#v", p)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		out := writeAll(t, "html").String()
+		for _, s := range []string{
+			"<!DOCTYPE html>",
+			"<td>TODO: </td><td>1</td>",
+			"<td>FIXME: </td><td>1</td>",
+			"a.go (1)",
+			"// TODO: hello",
+			"4: next",
+			"L3 func main",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("expected %q in %s", s, out)
+			}
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		rw, err := newRecordWriter("json", buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "[]\n" {
+			t.Errorf("exp=%#v out=%#v", "[]\n", buf.String())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := newRecordWriter("unknown", nil); err == nil {
+			t.Error("expected error but nil")
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package gotcha
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// lineRange is range of added lines, end is exclusive
+type lineRange struct {
+	start uint
+	end   uint
+}
+
+// Changes is changed files and added lines by git diff
+// paths are joined with the directory of git diff was run
+type Changes struct {
+	files map[string][]lineRange
+	dirs  map[string]bool
+}
+
+func newChanges() *Changes {
+	return &Changes{
+		files: make(map[string][]lineRange),
+		dirs:  make(map[string]bool),
+	}
+}
+
+func (c *Changes) addFile(path string) {
+	path = filepath.Clean(path)
+	if _, ok := c.files[path]; ok {
+		return
+	}
+	c.files[path] = nil
+	for dir := filepath.Dir(path); !c.dirs[dir]; dir = filepath.Dir(dir) {
+		c.dirs[dir] = true
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+}
+
+// hasFile reports whether path is changed, nil Changes contains all files
+func (c *Changes) hasFile(path string) bool {
+	if c == nil {
+		return true
+	}
+	_, ok := c.files[filepath.Clean(path)]
+	return ok
+}
+
+// hasDir reports whether dir have changed files, nil Changes contains all directories
+func (c *Changes) hasDir(dir string) bool {
+	if c == nil {
+		return true
+	}
+	return c.dirs[filepath.Clean(dir)]
+}
+
+// added reports whether line of path is added
+func (c *Changes) added(path string, line uint) bool {
+	if c == nil {
+		return true
+	}
+	for _, r := range c.files[filepath.Clean(path)] {
+		if r.start <= line && line < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// GitDiff run git diff --unified=0 on dir and return Changes
+// rev is revision range e.g. "main...HEAD", staged use --cached
+func GitDiff(dir, rev string, staged bool) (*Changes, error) {
+	// prefixes are fixed against diff.noprefix and diff.mnemonicPrefix of config
+	args := []string{"-c", "core.quotepath=off", "diff", "--unified=0", "--no-color", "--no-ext-diff", "--relative",
+		"--src-prefix=a/", "--dst-prefix=b/"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(args, "--")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git diff: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseUnifiedDiff(&stdout, dir)
+}
+
+// parseUnifiedDiff parse output of git diff --unified=0
+// lines of in hunk are counted, e.g. added "++ x" is not header of "+++ x"
+func parseUnifiedDiff(r io.Reader, dir string) (*Changes, error) {
+	var (
+		c    = newChanges()
+		sc   = bufio.NewScanner(r)
+		path string
+		// lines of left in the current hunk
+		olds, news uint64
+	)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024*16)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			// next file
+			olds, news = 0, 0
+		case olds != 0 && strings.HasPrefix(line, "-"):
+			olds--
+		case news != 0 && strings.HasPrefix(line, "+"):
+			news--
+		case strings.HasPrefix(line, `\`):
+			// \ No newline at end of file
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if name == "/dev/null" {
+				// deleted
+				path = ""
+				continue
+			}
+			path = filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, "b/")))
+			c.addFile(path)
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -l,s +l,s @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+				return nil, fmt.Errorf("git diff: invalid hunk: %q", line)
+			}
+			_, count, err := parseHunkRange(fields[1][1:])
+			if err != nil {
+				return nil, fmt.Errorf("git diff: invalid hunk: %q", line)
+			}
+			olds = count
+			start, count, err := parseHunkRange(fields[2][1:])
+			if err != nil {
+				return nil, fmt.Errorf("git diff: invalid hunk: %q", line)
+			}
+			news = count
+			if count != 0 && path != "" {
+				c.files[path] = append(c.files[path], lineRange{start: uint(start), end: uint(start + count)})
+			}
+		}
+	}
+	return c, sc.Err()
+}
+
+// parseHunkRange parse "l,s" of hunk header, s is 1 if omitted
+func parseHunkRange(s string) (start, count uint64, err error) {
+	nums := strings.SplitN(s, ",", 2)
+	if start, err = strconv.ParseUint(nums[0], 10, 0); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if len(nums) == 2 {
+		if count, err = strconv.ParseUint(nums[1], 10, 0); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// addedOnly drop matches of not on added lines
+func (g *Gotcha) addedOnly(gr *gatherRes) {
+	if !g.AddedOnly || g.Changes == nil {
+		return
+	}
+	var matches []*match
+	for _, m := range gr.matches {
+		if g.Changes.added(gr.path, m.num) {
+			matches = append(matches, m)
+		}
+	}
+	gr.matches = matches
+}
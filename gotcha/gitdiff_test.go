@@ -0,0 +1,150 @@
+package gotcha
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const unifiedDiff = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,0 +2,2 @@ package main
++// TODO: one
++// TODO: two
+@@ -10 +12 @@ func main() {
+-	old()
++	new()
+@@ -20,3 +21,0 @@ func f() {
+diff --git a/dir/b.go b/dir/b.go
+new file mode 100644
+--- /dev/null
++++ b/dir/b.go
+@@ -0,0 +1 @@
++package dir
+diff --git a/deleted.go b/deleted.go
+deleted file mode 100644
+--- a/deleted.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package deleted
+diff --git a/c.md b/c.md
+index 1111111..2222222 100644
+--- a/c.md
++++ b/c.md
+@@ -1,2 +1,2 @@
+--- x
+-y
++++ x
++y
+@@ -9,0 +10 @@ z
++TODO: second hunk
+`
+
+func Test_parseUnifiedDiff(t *testing.T) {
+	c, err := parseUnifiedDiff(strings.NewReader(unifiedDiff), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expfiles := map[string][]lineRange{
+		filepath.Join("root", "a.go"):        {{start: 2, end: 4}, {start: 12, end: 13}},
+		filepath.Join("root", "dir", "b.go"): {{start: 1, end: 2}},
+		filepath.Join("root", "c.md"):        {{start: 1, end: 3}, {start: 10, end: 11}},
+	}
+	if !reflect.DeepEqual(expfiles, c.files) {
+		t.Errorf("exp=%#v out=%#v", expfiles, c.files)
+	}
+	for _, dir := range []string{"root", filepath.Join("root", "dir"), "."} {
+		if !c.hasDir(dir) {
+			t.Errorf("expected has dir %s", dir)
+		}
+	}
+	if c.hasDir(filepath.Join("root", "other")) {
+		t.Error("unexpected has dir other")
+	}
+	if !c.added(filepath.Join("root", "a.go"), 3) || c.added(filepath.Join("root", "a.go"), 4) {
+		t.Error("unexpected added lines")
+	}
+	if c.hasFile(filepath.Join("root", "deleted.go")) {
+		t.Error("unexpected has deleted file")
+	}
+}
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root := filepath.Join(TestRoot, "git_diff")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(path, contents string) {
+		if err := ioutil.WriteFile(filepath.Join(root, path), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.txt", "TODO: old\n")
+	write("touched.txt", "TODO: old\n")
+	gitRun(t, root, "", "init", "-q")
+	gitRun(t, root, "", "add", ".")
+	gitRun(t, root, "", "commit", "-q", "-m", "init")
+	write("touched.txt", "TODO: old\nTODO: new\n")
+	write(filepath.Join("sub", "new.txt"), "TODO: new\n")
+	gitRun(t, root, "", "add", ".")
+
+	verify := func(t *testing.T, addedOnly bool, exp string) {
+		changes, err := GitDiff(root, "", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, work := range []string{"async", "sync"} {
+			g := NewGotcha()
+			errbuf := bytes.NewBufferString("")
+			g.Log.SetOutput(errbuf)
+			buf := bytes.NewBufferString("")
+			g.W = buf
+			g.Sort = "path"
+			g.Changes = changes
+			g.AddedOnly = addedOnly
+			var res *Result
+			if work == "async" {
+				res = g.WorkGo(context.Background(), root, 0)
+			} else {
+				res = g.SyncWorkGo(context.Background(), root)
+			}
+			if len(res.Errors) != 0 {
+				t.Fatal(res.Errors)
+			}
+			if exp != buf.String() {
+				t.Errorf("%s: exp=%#v out=%#v", work, exp, buf.String())
+			}
+		}
+	}
+	t.Run("changed files", func(t *testing.T) {
+		exp := filepath.Join(root, "sub", "new.txt") + "\nL1:TODO: new\n\n" +
+			filepath.Join(root, "touched.txt") + "\nL1:TODO: old\nL2:TODO: new\n\n"
+		verify(t, false, exp)
+	})
+	t.Run("added only", func(t *testing.T) {
+		exp := filepath.Join(root, "sub", "new.txt") + "\nL1:TODO: new\n\n" +
+			filepath.Join(root, "touched.txt") + "\nL2:TODO: new\n\n"
+		verify(t, true, exp)
+	})
+	t.Run("prefixes of config", func(t *testing.T) {
+		exp := filepath.Join(root, "sub", "new.txt") + "\nL1:TODO: new\n\n" +
+			filepath.Join(root, "touched.txt") + "\nL2:TODO: new\n\n"
+		gitRun(t, root, "", "config", "diff.mnemonicPrefix", "true")
+		verify(t, true, exp)
+		gitRun(t, root, "", "config", "diff.noprefix", "true")
+		verify(t, true, exp)
+	})
+}
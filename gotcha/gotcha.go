@@ -0,0 +1,1235 @@
+// Package gotcha gather words like "TODO: " from files of recursive
+//
+// Search yield each matches to callback, WorkGo and SyncWorkGo write
+// them to W by Format. cmd/gotcha is the CLI of this package.
+package gotcha
+
+// TODO: to simpl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Default Ignores
+var (
+	IgnoreDirs = []string{
+		".git",
+		".cache",
+	}
+	IgnoreBases = []string{
+		ConfigName,
+	}
+	// fast path of skip binaries, others are detected by contents
+	IgnoreTypes = []string{
+		".iso", ".img",
+		".log", ".prof",
+		".pgp", ".ttf", ".pdf",
+		".jpg", ".jpeg", ".png", ".ico", ".gif",
+		".mp4",
+		".mp3", ".ogg", ".wav", ".au",
+		".so", ".mo", ".a", ".o", ".pyc", ".exe", ".efi",
+		".gz", ".xz", ".tar", ".bz", ".bz2", ".db", ".tgz", ".zip",
+	}
+)
+
+// version and name
+const (
+	Version = "0.3.1dev"
+	Name    = "gotcha"
+)
+
+// Gotcha for search recursive
+type Gotcha struct {
+	W   io.Writer
+	Log *log.Logger
+
+	// options
+	Words          []string
+	TypesMap       map[string]bool
+	IgnoreDirsMap  map[string]bool
+	IgnoreBasesMap map[string]bool
+	IgnoreTypesMap map[string]bool
+
+	// limit of runes of output lines, long lines are excerpted around the word
+	MaxRune int
+	Add     uint
+	Before  uint
+	Trim    bool
+	Abort   bool
+	Group   bool
+	Format  string
+
+	// drop matches of outside comments if known language
+	CommentsOnly bool
+
+	// attach git blame to matches
+	Blame bool
+	// drop matches of newer than OlderThan, require Blame
+	OlderThan time.Duration
+	// sort order of output, "" is order of walk
+	Sort string
+	// WorkGo emit results in lexical walk order, same as SyncWorkGo
+	Ordered bool
+
+	// honour IgnoreFiles while walking
+	GitIgnore bool
+	// walk into symbolic links of directories and files, loops are detected by inode
+	Follow bool
+	// do not walk into directories of other device than the root
+	OneFileSystem bool
+	// limit of depth of walk, 1 is files of the root only and 0 is unlimited
+	MaxDepth uint
+
+	// limit to changed files if not nil
+	Changes *Changes
+	// drop matches of not on added lines of Changes
+	AddedOnly bool
+
+	// read compressed files and members of archives, see decompress.go
+	Decompress bool
+
+	// patterns of Annotation of after the word, see annotation.go
+	Annotations []*regexp.Regexp
+	// drop matches of not annotated by the owner or the issue if not empty
+	Owner string
+	Issue string
+	// drop matches of not overdue
+	Overdue bool
+
+	// encoding of files, EncodingAuto detect BOM and common encodings
+	// names except built in are transcoded by iconv command
+	Encoding string
+	// Encoding by file extension
+	EncodingsMap map[string]string
+
+	// mode of binary files, one of Binaries
+	Binary string
+	// warnings for Binary of "warn"
+	Warn *log.Logger
+
+	// record all matches to Result.Matches
+	Keep bool
+
+	// rewrite matched words by template if not empty, see replace.go
+	Replace string
+	// write unified diff of Replace to W instead of rewrite and records
+	DryRun bool
+
+	// attach enclosing symbols to matches by SymbolFinders, see symbol.go
+	Symbols bool
+
+	// decoration of text format, see color.go
+	Color bool
+	// OSC 8 hyperlinks of file URL on paths and line numbers
+	Hyperlink bool
+	// "path:line:col: text" per match as quickfix of editors
+	EditorFormat bool
+
+	// counters, errors and kept matches of works, guarded by mu
+	mu      sync.Mutex
+	nfiles  uint
+	nlines  uint
+	ntags   map[string]uint
+	errs    []error
+	matches []*Match
+
+	// files of replaced by Replace, a file of multiple paths is replaced once
+	replaced map[fileID]bool
+
+	// results for Group and Sort, flush on end of work
+	held []*gatherRes
+	// writer for Format, create on first write
+	rw recordWriter
+}
+
+// NewGotcha allocation for Gotcha
+func NewGotcha() *Gotcha {
+	makeBoolMap := func(list []string) map[string]bool {
+		m := make(map[string]bool)
+		for _, s := range list {
+			m[s] = true
+		}
+		return m
+	}
+	return &Gotcha{
+		W:   os.Stdout,
+		Log: log.New(os.Stderr, "["+Name+"]:", log.Lshortfile),
+
+		Words:          []string{"TODO: "},
+		TypesMap:       make(map[string]bool),
+		IgnoreDirsMap:  makeBoolMap(IgnoreDirs),
+		IgnoreBasesMap: makeBoolMap(IgnoreBases),
+		IgnoreTypesMap: makeBoolMap(IgnoreTypes),
+
+		MaxRune: 256,
+		Add:     0,
+		Before:  0,
+		Trim:    false,
+		Abort:   false,
+		Group:   false,
+		Format:  "text",
+
+		CommentsOnly: false,
+
+		Blame:     false,
+		OlderThan: 0,
+		Sort:      "",
+		Ordered:   true,
+
+		GitIgnore: true,
+
+		Changes:   nil,
+		AddedOnly: false,
+
+		Decompress: false,
+
+		Annotations: defaultAnnotations,
+
+		Encoding:     EncodingAuto,
+		EncodingsMap: make(map[string]string),
+
+		Binary: "skip",
+		Warn:   log.New(os.Stderr, "["+Name+"]:", 0),
+
+		nfiles: 0,
+		nlines: 0,
+		ntags:  make(map[string]uint),
+	}
+}
+
+// PrintTotal prnt nfiles and ncontents
+// if have multiple words then nlines break down by tag
+func (g *Gotcha) PrintTotal() (int, error) {
+	res := g.result()
+	n, err := fmt.Fprintf(g.W, "files %d\nlines %d\nerrors %d\n", res.Files, res.Lines, len(res.Errors))
+	if err != nil || len(g.Words) < 2 {
+		return n, err
+	}
+	for _, tag := range g.Words {
+		i, err := fmt.Fprintf(g.W, "lines %q %d\n", tag, res.Tags[tag])
+		n += i
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// count the gathered result
+func (g *Gotcha) count(gr *gatherRes) {
+	if len(gr.matches) == 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Keep {
+		g.matches = append(g.matches, gr.toMatches()...)
+	}
+	g.nfiles++
+	g.nlines += uint(len(gr.matches))
+	for _, m := range gr.matches {
+		g.ntags[m.tag]++
+	}
+}
+
+// Result is summary of the last work of Gotcha, counters are reset on each work
+type Result struct {
+	// number of files of have matches
+	Files uint
+	// number of matches and break down by tag
+	Lines uint
+	Tags  map[string]uint
+	// errors of files, a work does not stop by them unless Abort
+	Errors []error
+	// all matches if Keep
+	Matches []*Match
+}
+
+// result return current Result
+func (g *Gotcha) result() *Result {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tags := make(map[string]uint, len(g.ntags))
+	for tag, n := range g.ntags {
+		tags[tag] = n
+	}
+	return &Result{
+		Files:   g.nfiles,
+		Lines:   g.nlines,
+		Tags:    tags,
+		Errors:  append([]error(nil), g.errs...),
+		Matches: append([]*Match(nil), g.matches...),
+	}
+}
+
+// reset clear counters, errors, kept matches and held results of the previous work
+func (g *Gotcha) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nfiles, g.nlines = 0, 0
+	g.ntags = make(map[string]uint)
+	g.errs, g.matches = nil, nil
+	g.held, g.rw = nil, nil
+	g.replaced = nil
+}
+
+// fail record err to Result
+func (g *Gotcha) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs = append(g.errs, err)
+}
+
+// report record err of a file, return err if Abort
+func (g *Gotcha) report(err error) error {
+	if g.Abort {
+		return err
+	}
+	g.fail(err)
+	return nil
+}
+
+// emit replace, write and count gr
+func (g *Gotcha) emit(gr *gatherRes) error {
+	if err := gr.Err(); err != nil {
+		return g.report(err)
+	}
+	if g.Replace != "" {
+		if err := g.replace(gr); err != nil {
+			return g.report(err)
+		}
+	}
+	if g.Replace == "" || !g.DryRun {
+		if err := g.write(gr); err != nil {
+			return err
+		}
+	}
+	g.count(gr)
+	return nil
+}
+
+// writer return recordWriter for g.Format
+func (g *Gotcha) writer() (recordWriter, error) {
+	if g.rw != nil {
+		return g.rw, nil
+	}
+	rw, err := newRecordWriter(g.Format, g.W)
+	if err != nil {
+		return nil, err
+	}
+	if tw, ok := rw.(*textWriter); ok {
+		tw.style = g.textStyle()
+	}
+	g.rw = rw
+	return rw, nil
+}
+
+// write gr to g.W, if use Group or Sort then hold until flush
+func (g *Gotcha) write(gr *gatherRes) error {
+	if err := gr.Err(); err != nil {
+		return err
+	}
+	if g.Group || g.Sort != "" {
+		if len(gr.matches) != 0 {
+			g.held = append(g.held, gr)
+		}
+		return nil
+	}
+	rw, err := g.writer()
+	if err != nil {
+		return err
+	}
+	return rw.write(gr)
+}
+
+// flush write held results with sort and per tag groups, and close the writer
+func (g *Gotcha) flush() error {
+	rw, err := g.writer()
+	if err != nil {
+		return err
+	}
+	defer func() { g.rw = nil }()
+	defer func() { g.held = nil }()
+
+	results := g.held
+	if g.Group && g.Sort == "" {
+		sort.Slice(results, func(i, j int) bool { return results[i].path < results[j].path })
+	}
+	results = g.sortResults(results)
+	switch {
+	case g.Group:
+		for _, tag := range g.Words {
+			if g.ntags[tag] == 0 {
+				continue
+			}
+			if err := rw.group(tag); err != nil {
+				return err
+			}
+			for _, gr := range results {
+				if err := rw.write(gr.filter(tag)); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		for _, gr := range results {
+			if err := rw.write(gr); err != nil {
+				return err
+			}
+		}
+	}
+	return rw.flush()
+}
+
+func (g *Gotcha) isTarget(path string) bool {
+	if g.IgnoreBasesMap[path] {
+		return false
+	}
+	if g.Decompress && isCompressed(path) {
+		return true
+	}
+	ext := filepath.Ext(path)
+	if g.IgnoreTypesMap[ext] {
+		return false
+	}
+	if len(g.TypesMap) == 0 {
+		return true
+	}
+	return g.TypesMap[ext]
+}
+
+// Match is a line of contains the word, also record of structured output
+type Match struct {
+	// path of the file as walked from root
+	Path string `json:"path"`
+	// line number and rune index of the word, 1 origin
+	Line uint   `json:"line"`
+	Col  int    `json:"column"`
+	Tag  string `json:"tag"`
+	// the line, excerpted by MaxRune and without the tag if Trim
+	Text string `json:"text"`
+	// lines of before and after the match by Before and Add
+	Before  []string `json:"before,omitempty"`
+	Context []string `json:"context,omitempty"`
+	// git blame if Blame
+	Blame *Blame `json:"blame,omitempty"`
+	// parsed of after the word, nil if not annotated
+	Annotation *Annotation `json:"annotation,omitempty"`
+	// enclosing function or type if Symbols, e.g. "func (*Gotcha) gather"
+	Symbol string `json:"symbol,omitempty"`
+
+	// byte index of the tag in Text + 1, 0 if unknown
+	tagAt int
+	// bytes of the word in Text if annotated after the stem, 0 is length of Tag
+	wordLen int
+}
+
+// toMatches convert gatherRes to Match
+func (gr *gatherRes) toMatches() []*Match {
+	var ms []*Match
+	for _, m := range gr.matches {
+		ms = append(ms, &Match{
+			Path:       gr.path,
+			Line:       m.num,
+			Col:        m.col,
+			Tag:        m.tag,
+			Text:       m.text,
+			Before:     m.befores,
+			Context:    m.adds,
+			Blame:      m.blame,
+			Annotation: m.annotation,
+			Symbol:     m.symbol,
+			tagAt:      m.tagAt,
+			wordLen:    m.wordLen,
+		})
+	}
+	return ms
+}
+
+// match is a line of contains the word
+type match struct {
+	num     uint   // line number
+	col     int    // column of tag in runes, 1 origin
+	tag     string // hit word
+	text    string
+	adds    []string // lines of after the match
+	befores []string // lines of before the match
+
+	blame      *Blame
+	annotation *Annotation
+	symbol     string // enclosing function or type
+	tagAt      int    // byte index of the tag in text + 1, 0 if not in text e.g. Trim
+	wordLen    int    // bytes of the word if annotated after the stem e.g. "TODO(alice): ", 0 is len(tag)
+}
+
+// TODO: consider name
+type gatherRes struct {
+	path    string
+	matches []*match
+	err     error
+}
+
+// filter return gatherRes that have only matches of the tag
+func (gr *gatherRes) filter(tag string) *gatherRes {
+	res := &gatherRes{path: gr.path, err: gr.err}
+	for _, m := range gr.matches {
+		if m.tag == tag {
+			res.matches = append(res.matches, m)
+		}
+	}
+	return res
+}
+
+func (gr *gatherRes) Err() error {
+	return gr.err
+}
+
+func (gr *gatherRes) Fwrite(w io.Writer) error {
+	return gr.fwrite(w, textStyle{})
+}
+
+// fwrite write gr as text format decorated by st
+func (gr *gatherRes) fwrite(w io.Writer, st textStyle) error {
+	if err := gr.Err(); err != nil {
+		return err
+	}
+	if len(gr.matches) == 0 {
+		return nil
+	}
+	if st.editor {
+		return gr.fwriteEditor(w, st)
+	}
+	var contents []string
+	for i, m := range gr.matches {
+		// separate hunks of context like grep
+		if gr.separated(i) {
+			contents = append(contents, st.paint(sgrSep, "--"))
+		}
+		for j, s := range m.befores {
+			num := fmt.Sprint(m.num - uint(len(m.befores)-j))
+			contents = append(contents, fmt.Sprintf(" %s:%s", st.paint(sgrLine, num), s))
+		}
+		label := st.link(gr.path, m.num, st.paint(sgrLine, fmt.Sprintf("L%v", m.num)))
+		if m.symbol != "" {
+			label += " (" + m.symbol + ")"
+		}
+		if m.blame != nil {
+			contents = append(contents, fmt.Sprintf("%s:%s [%s]", label, st.word(m), m.blame))
+		} else {
+			contents = append(contents, fmt.Sprintf("%s:%s", label, st.word(m)))
+		}
+		for j, s := range m.adds {
+			num := fmt.Sprint(m.num + uint(j) + 1)
+			contents = append(contents, fmt.Sprintf(" %s:%s", st.paint(sgrLine, num), s))
+		}
+	}
+	path := st.link(gr.path, 0, st.paint(sgrPath, gr.path))
+	_, err := fmt.Fprintf(w, "%s\n%s\n\n", path, strings.Join(contents, "\n"))
+	return err
+}
+
+// separated reports whether context of i-th match is not continued from the previous
+func (gr *gatherRes) separated(i int) bool {
+	if i == 0 {
+		return false
+	}
+	m, prev := gr.matches[i], gr.matches[i-1]
+	gap := m.num-uint(len(m.befores)) > prev.num+uint(len(prev.adds))+1
+	return gap && (len(prev.adds) != 0 || len(m.befores) != 0)
+}
+
+// fwriteEditor write gr as "path:line:col: text" per match for quickfix of editors
+// lines of context are "path-line-text" and hunks are separated by "--" like grep
+func (gr *gatherRes) fwriteEditor(w io.Writer, st textStyle) error {
+	path := st.paint(sgrPath, gr.path)
+	var lines []string
+	for i, m := range gr.matches {
+		if gr.separated(i) {
+			lines = append(lines, st.paint(sgrSep, "--"))
+		}
+		for j, s := range m.befores {
+			num := fmt.Sprint(m.num - uint(len(m.befores)-j))
+			lines = append(lines, fmt.Sprintf("%s-%s-%s", path, st.paint(sgrLine, num), s))
+		}
+		loc := st.link(gr.path, m.num, fmt.Sprintf("%s:%s:%d:", path, st.paint(sgrLine, fmt.Sprint(m.num)), m.col))
+		if m.symbol != "" {
+			loc += " (" + m.symbol + ")"
+		}
+		if m.blame != nil {
+			lines = append(lines, fmt.Sprintf("%s %s [%s]", loc, st.word(m), m.blame))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s", loc, st.word(m)))
+		}
+		for j, s := range m.adds {
+			num := fmt.Sprint(m.num + uint(j) + 1)
+			lines = append(lines, fmt.Sprintf("%s-%s-%s", path, st.paint(sgrLine, num), s))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// ring is ring buffer of lines for before context
+type ring struct {
+	buf  []string
+	head int // next write
+	n    int
+}
+
+func newRing(size uint) *ring {
+	return &ring{buf: make([]string, size)}
+}
+
+func (r *ring) push(s string) {
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.head] = s
+	r.head = (r.head + 1) % len(r.buf)
+	if r.n < len(r.buf) {
+		r.n++
+	}
+}
+
+// lines return lines of oldest first and reset
+func (r *ring) lines() []string {
+	if r.n == 0 {
+		return nil
+	}
+	lines := make([]string, 0, r.n)
+	for i := len(r.buf) - r.n; i < len(r.buf); i++ {
+		lines = append(lines, r.buf[(r.head+i)%len(r.buf)])
+	}
+	r.n = 0
+	return lines
+}
+
+// excerpt return s of limited to max runes around byte index i
+// cut sides are marked by "..."
+func excerpt(s string, i, max int) string {
+	res, _ := excerptIndex(s, i, max)
+	return res
+}
+
+// excerptIndex is excerpt and return byte index of i in the result, -1 if cut
+func excerptIndex(s string, i, max int) (string, int) {
+	const mark = "..."
+	n := utf8.RuneCountInString(s)
+	if max <= 0 || n <= max {
+		return s, i
+	}
+	runes := []rune(s)
+	// put index on quarter of the window
+	ri := utf8.RuneCountInString(s[:i])
+	start := ri - max/4
+	if start > n-max {
+		start = n - max
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + max
+	res := string(runes[start:end])
+	at := -1
+	if start <= ri && ri < end {
+		at = len(string(runes[start:ri]))
+	}
+	if start != 0 {
+		res = mark + res
+		if at != -1 {
+			at += len(mark)
+		}
+	}
+	if end != n {
+		res += mark
+	}
+	return res, at
+}
+
+// index return first index of any words in s, length of the matched and the word
+// the word of annotated after the stem is matched e.g. "TODO(alice): " of "TODO: "
+// accept filter the index if not nil
+func (g *Gotcha) index(s string, accept func(i int) bool) (int, int, string) {
+	index, size, tag := -1, 0, ""
+	for _, word := range g.Words {
+		for off := 0; off <= len(s); {
+			i := strings.Index(s[off:], word)
+			if i == -1 {
+				break
+			}
+			i += off
+			if accept == nil || accept(i) {
+				if index == -1 || i < index {
+					index, size, tag = i, len(word), word
+				}
+				break
+			}
+			off = i + 1
+		}
+		head, tail := splitWord(word)
+		if tail == "" || len(g.Annotations) == 0 {
+			continue
+		}
+		for off := 0; off < len(s); {
+			i := strings.Index(s[off:], head)
+			if i == -1 || index != -1 && off+i >= index {
+				break
+			}
+			i += off
+			if n := g.annotatedLen(s[i+len(head):], tail); n != 0 && (accept == nil || accept(i)) {
+				index, size, tag = i, len(head)+n, word
+				break
+			}
+			off = i + 1
+		}
+	}
+	return index, size, tag
+}
+
+func (g *Gotcha) gather(path string) *gatherRes {
+	f, err := os.Open(path)
+	if err != nil {
+		return &gatherRes{path: path, err: err}
+	}
+	defer f.Close()
+
+	gr := g.gatherReader(path, path, f)
+	if gr.err != nil {
+		return gr
+	}
+	g.addedOnly(gr)
+	if g.Blame {
+		if err := g.blame(gr); err != nil {
+			g.Log.Printf("%v\n\n", err)
+		}
+		g.olderThan(gr)
+	}
+	return gr
+}
+
+// gatherReader gather lines of r as path, name is used for detect language
+func (g *Gotcha) gatherReader(path, name string, r io.Reader) *gatherRes {
+	gr := &gatherRes{path: path}
+	decoded, closeDecoded, err := g.decode(path, bufio.NewReaderSize(r, sniffLen), g.encodingOf(name))
+	if err != nil {
+		gr.err = err
+		return gr
+	}
+	closed := false
+	defer func() {
+		if !closed {
+			closeDecoded()
+		}
+	}()
+	br, ok := decoded.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(decoded, sniffLen)
+	}
+
+	var (
+		src       io.Reader = br
+		accept    func(i int) bool
+		lineCount = uint(1) // TODO: consider to zero
+		last      *match
+		before    = newRing(g.Before)
+	)
+	if g.Binary != "scan" {
+		sample, err := br.Peek(sniffLen)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			gr.err = err
+			return gr
+		}
+		if isBinary(sample) {
+			if g.Binary == "warn" {
+				g.Warn.Printf("binary file: [%v]\n", path)
+			} else {
+				g.Log.Printf("ignored binary: [%v]\n\n", path)
+			}
+			return gr
+		}
+	}
+	// own records of e.g. baseline and tracker
+	if head, _ := br.Peek(len(recordMark)); string(head) == recordMark {
+		g.Log.Printf("ignored record of %s: [%v]\n\n", Name, path)
+		return gr
+	}
+	var (
+		findComments commentFinder
+		findSymbols  SymbolFinder
+		all          []byte
+	)
+	if g.CommentsOnly {
+		findComments = commentFinderFor(name)
+	}
+	if g.Symbols {
+		findSymbols = symbolFinderFor(name)
+	}
+	if findComments != nil || findSymbols != nil {
+		all, gr.err = ioutil.ReadAll(br)
+		if gr.err != nil {
+			return gr
+		}
+		src = bytes.NewReader(all)
+	}
+	if findComments != nil {
+		comments := findComments(all)
+		accept = func(i int) bool { return comments.contains(lineCount, i) }
+	}
+	lr, ok := src.(*bufio.Reader)
+	if !ok {
+		lr = bufio.NewReader(src)
+	}
+
+	for ; ; lineCount++ {
+		// any length
+		text, err := lr.ReadString('\n')
+		if len(text) == 0 && err != nil {
+			if err != io.EOF {
+				gr.err = err
+			}
+			break
+		}
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+
+		if index, size, tag := g.index(text, accept); index != -1 {
+			last = &match{
+				num:        lineCount,
+				col:        utf8.RuneCountInString(text[:index]) + 1,
+				tag:        tag,
+				befores:    before.lines(),
+				annotation: g.annotate(text[index+size:]),
+			}
+			if size != len(tag) {
+				head, _ := splitWord(tag)
+				last.annotation = g.annotate(text[index+len(head):])
+				last.wordLen = size
+			}
+			if g.Trim {
+				last.text = excerpt(text[index+size:], 0, g.MaxRune)
+			} else {
+				var at int
+				last.text, at = excerptIndex(text, index, g.MaxRune)
+				last.tagAt = at + 1
+			}
+			gr.matches = append(gr.matches, last)
+			continue
+		}
+		if last != nil && uint(len(last.adds)) < g.Add {
+			last.adds = append(last.adds, excerpt(text, 0, g.MaxRune))
+			continue
+		}
+		last = nil
+		before.push(excerpt(text, 0, g.MaxRune))
+	}
+	closed = true
+	if err := closeDecoded(); err != nil && gr.err == nil {
+		gr.err = fmt.Errorf("%s: %v", path, err)
+	}
+	if findSymbols != nil {
+		attachSymbols(gr, all, findSymbols)
+	}
+	g.filterAnnotations(gr)
+	return gr
+}
+
+// rootIgnorer return ignorer for walk of root, return nil if not use GitIgnore
+func (g *Gotcha) rootIgnorer(root string) *ignorer {
+	if !g.GitIgnore {
+		return nil
+	}
+	ig, err := newIgnorer(root)
+	if err != nil {
+		g.Log.Printf("%v\n\n", err)
+		return nil
+	}
+	return ig
+}
+
+// childIgnorer return ignorer for dir, errors are logged
+func (g *Gotcha) childIgnorer(ig *ignorer, dir string) *ignorer {
+	if ig == nil {
+		return nil
+	}
+	child, err := ig.child(dir)
+	if err != nil {
+		g.Log.Printf("%v\n\n", err)
+	}
+	return child
+}
+
+// walkDir is queued directory with ignore rules of the parent
+type walkDir struct {
+	path string
+	ig   *ignorer
+	// depth from the root, root is 0
+	depth uint
+	// device of the root for OneFileSystem
+	dev uint64
+	// directories of from the root to this for loop detection of Follow
+	parents []fileID
+}
+
+// fileID identify a file by device and inode, path if not available
+type fileID struct {
+	dev, ino uint64
+	path     string
+}
+
+// newWalkDir return walkDir of the root
+func (g *Gotcha) newWalkDir(root string, info os.FileInfo) walkDir {
+	id := fileIDOf(root, info)
+	dir := walkDir{path: root, ig: g.rootIgnorer(root), dev: id.dev}
+	if g.Follow {
+		dir.parents = []fileID{id}
+	}
+	return dir
+}
+
+// child return walkDir of the directory of under dir
+// false if limited by MaxDepth and OneFileSystem or a loop of Follow
+func (g *Gotcha) child(dir walkDir, path string, info os.FileInfo, ig *ignorer) (walkDir, bool) {
+	if g.MaxDepth != 0 && dir.depth+1 >= g.MaxDepth {
+		return walkDir{}, false
+	}
+	child := walkDir{path: path, ig: ig, depth: dir.depth + 1, dev: dir.dev}
+	if !g.OneFileSystem && !g.Follow {
+		return child, true
+	}
+	id := fileIDOf(path, info)
+	if g.OneFileSystem && id.dev != dir.dev {
+		return walkDir{}, false
+	}
+	if g.Follow {
+		for _, p := range dir.parents {
+			if p == id {
+				g.Log.Printf("symlink loop: [%v]\n\n", path)
+				return walkDir{}, false
+			}
+		}
+		child.parents = append(dir.parents[:len(dir.parents):len(dir.parents)], id)
+	}
+	return child, true
+}
+
+// follow return info of the target if info is a symbolic link and Follow
+func (g *Gotcha) follow(path string, info os.FileInfo) (os.FileInfo, error) {
+	if !g.Follow || info.Mode()&os.ModeSymlink == 0 {
+		return info, nil
+	}
+	return os.Stat(path)
+}
+
+// gatherJob is a file to gather, seq is position in walk order
+type gatherJob struct {
+	seq  int
+	path string
+}
+
+// seqRes is results of gatherJob, archives have multiple results
+type seqRes struct {
+	seq int
+	grs []*gatherRes
+}
+
+// walkTarget classify the entry of directory for walk
+func (g *Gotcha) walkTarget(path string, info os.FileInfo, ig *ignorer) (dir, file bool) {
+	switch {
+	case info.IsDir():
+		return !g.IgnoreDirsMap[info.Name()] && !ig.ignored(path, true) && g.Changes.hasDir(path), false
+	case info.Mode().IsRegular():
+		return false, g.isTarget(info.Name()) && !ig.ignored(path, false) && g.Changes.hasFile(path)
+	}
+	return false, false
+}
+
+// entry classify the entry of dir by Follow, walkTarget and limits of child
+// ig is ignore rules of entries of dir, child is valid if isDir
+func (g *Gotcha) entry(dir walkDir, ig *ignorer, path string, info os.FileInfo) (child walkDir, isDir, isFile bool) {
+	linked := info.Mode()&os.ModeSymlink != 0
+	info, err := g.follow(path, info)
+	if err != nil {
+		g.Log.Printf("ignored: [%v]: %v\n\n", path, err)
+		return walkDir{}, false, false
+	}
+	isDir, isFile = g.walkTarget(path, info, ig)
+	if isFile && linked && g.OneFileSystem && fileIDOf(path, info).dev != dir.dev {
+		isFile = false
+	}
+	if isDir {
+		child, isDir = g.child(dir, path, info, ig)
+	}
+	if !isDir && !isFile {
+		g.Log.Printf("ignored: [%v]\n\n", path)
+	}
+	return child, isDir, isFile
+}
+
+// walk call visit with target files of dir in lexical order
+func (g *Gotcha) walk(ctx context.Context, dir walkDir, visit func(path string) error) error {
+	return g.walkWith(ctx, dir, nil, visit)
+}
+
+// walkWith is walk, enter is called with each directory and ignore rules of the entries before read
+// nil enter is nothing
+func (g *Gotcha) walkWith(ctx context.Context, dir walkDir, enter func(dir walkDir, ig *ignorer) error, visit func(path string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ig := g.childIgnorer(dir.ig, dir.path)
+	if enter != nil {
+		if err := enter(dir, ig); err != nil {
+			return err
+		}
+	}
+	infos, err := ioutil.ReadDir(dir.path)
+	if err != nil {
+		return g.report(err)
+	}
+	for _, info := range infos {
+		path := filepath.Join(dir.path, info.Name())
+		switch child, isDir, isFile := g.entry(dir, ig, path, info); {
+		case isDir:
+			if err := g.walkWith(ctx, child, enter, visit); err != nil {
+				return err
+			}
+		case isFile:
+			if err := visit(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkRoot call visit with root if root is a file, otherwise walk root
+// a file of root is gathered regardless of ignores
+func (g *Gotcha) walkRoot(ctx context.Context, root string, visit func(path string) error) error {
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	switch {
+	case err != nil:
+		return g.report(err)
+	case info.IsDir():
+		return g.walk(ctx, g.newWalkDir(root, info), visit)
+	case !info.Mode().IsRegular():
+		return g.report(fmt.Errorf("invalid file type: [%v]", root))
+	case g.Changes.hasFile(root):
+		return visit(root)
+	}
+	return nil
+}
+
+// dedupeRoots drop roots of same or under the other root, order is kept
+func dedupeRoots(roots []string) []string {
+	if len(roots) < 2 {
+		return roots
+	}
+	abs := make([]string, len(roots))
+	first := make(map[string]int)
+	for i, root := range roots {
+		a, err := filepath.Abs(root)
+		if err != nil {
+			a = filepath.Clean(root)
+		}
+		abs[i] = a
+		if _, ok := first[a]; !ok {
+			first[a] = i
+		}
+	}
+	var res []string
+	for i, root := range roots {
+		if first[abs[i]] != i {
+			continue
+		}
+		// under the other root
+		dup := false
+		for dir := abs[i]; !dup && filepath.Dir(dir) != dir; {
+			dir = filepath.Dir(dir)
+			_, dup = first[dir]
+		}
+		if !dup {
+			res = append(res, root)
+		}
+	}
+	return res
+}
+
+// workAsync gather files of roots by nworker and call consume with results
+// if Ordered then results are consumed in lexical walk order by reorder buffer
+// the work stop by cancel of ctx or error of consume
+func (g *Gotcha) workAsync(ctx context.Context, roots []string, nworker uint, consume func(gr *gatherRes) error) error {
+	if nworker == 0 {
+		nworker = uint(runtime.NumCPU())
+	}
+	// walker -> jobs -> workers -> res -> consumer
+	var (
+		grp, gctx = withGroup(ctx)
+		jobs      = make(chan gatherJob, 512)
+		res       = make(chan seqRes, 512)
+		workers   = new(sync.WaitGroup)
+	)
+
+	grp.Go(func() error {
+		defer close(jobs)
+		seq := 0
+		send := func(path string) error {
+			select {
+			case jobs <- gatherJob{seq: seq, path: path}:
+				seq++
+				return nil
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		}
+		for _, root := range dedupeRoots(roots) {
+			if err := g.walkRoot(gctx, root, send); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	for i := uint(0); i != nworker; i++ {
+		workers.Add(1)
+		grp.Go(func() error {
+			defer workers.Done()
+			for job := range jobs {
+				select {
+				case res <- seqRes{seq: job.seq, grs: g.gatherFile(job.path)}:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+	go func() {
+		workers.Wait()
+		close(res)
+	}()
+
+	grp.Go(func() error {
+		// reorder buffer for Ordered
+		var (
+			next    = 0
+			pending = make(map[int][]*gatherRes)
+		)
+		consumeAll := func(grs []*gatherRes) error {
+			for _, gr := range grs {
+				if err := consume(gr); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for r := range res {
+			if !g.Ordered {
+				if err := consumeAll(r.grs); err != nil {
+					return err
+				}
+				continue
+			}
+			pending[r.seq] = r.grs
+			for grs, ok := pending[next]; ok; grs, ok = pending[next] {
+				delete(pending, next)
+				next++
+				if err := consumeAll(grs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return grp.Wait()
+}
+
+// workSync gather files of roots and call consume with results in walk order
+func (g *Gotcha) workSync(ctx context.Context, roots []string, consume func(gr *gatherRes) error) error {
+	consumeFile := func(path string) error {
+		for _, gr := range g.gatherFile(path) {
+			if err := consume(gr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range dedupeRoots(roots) {
+		if err := g.walkRoot(ctx, root, consumeFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WorkGo run on async, gather files by nworker and write them to W
+// root is a directory or a file
+// the work stop by cancel of ctx, write error or any error if Abort
+func (g *Gotcha) WorkGo(ctx context.Context, root string, nworker uint) *Result {
+	return g.WorkGoRoots(ctx, []string{root}, nworker)
+}
+
+// WorkGoRoots is WorkGo for multiple roots, roots of under the other are dropped
+func (g *Gotcha) WorkGoRoots(ctx context.Context, roots []string, nworker uint) *Result {
+	g.reset()
+	if err := g.workAsync(ctx, roots, nworker, g.emit); err != nil {
+		g.fail(err)
+	}
+	if err := g.flush(); err != nil {
+		g.fail(err)
+	}
+	return g.result()
+}
+
+// SyncWorkGo run on sync
+func (g *Gotcha) SyncWorkGo(ctx context.Context, root string) *Result {
+	return g.SyncWorkGoRoots(ctx, []string{root})
+}
+
+// SyncWorkGoRoots is SyncWorkGo for multiple roots
+func (g *Gotcha) SyncWorkGoRoots(ctx context.Context, roots []string) *Result {
+	g.reset()
+	if err := g.workSync(ctx, roots, g.emit); err != nil {
+		g.fail(err)
+	}
+	if err := g.flush(); err != nil {
+		g.fail(err)
+	}
+	return g.result()
+}
+
+// Search call fn with each matches of root, W, Format, Group and Sort are not used
+// the search stop by cancel of ctx or error of fn, the error is in Result.Errors
+func (g *Gotcha) Search(ctx context.Context, root string, fn func(m *Match) error) *Result {
+	g.reset()
+	err := g.workAsync(ctx, []string{root}, 0, func(gr *gatherRes) error {
+		if err := gr.Err(); err != nil {
+			return g.report(err)
+		}
+		g.count(gr)
+		for _, m := range gr.toMatches() {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		g.fail(err)
+	}
+	return g.result()
+}
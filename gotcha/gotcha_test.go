@@ -0,0 +1,918 @@
+package gotcha
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var TestRoot = "t"
+
+// gitRun run git in dir as alice, date of author and committer if not empty
+// global and system config are ignored e.g. signing of commits
+func gitRun(t *testing.T, dir, date string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+	)
+	if date != "" {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	}
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, b)
+	}
+}
+
+const TooLongLine = `too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line too long line`
+
+func Test_gather(t *testing.T) {
+	path := filepath.Join(TestRoot, "gather.txt")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	type Tests struct {
+		in  string
+		exp *gatherRes
+	}
+
+	verify := func(t *testing.T, g *Gotcha, tests []Tests) {
+		for _, test := range tests {
+			f.Truncate(0)
+			f.Seek(0, 0)
+			f.WriteString(test.in)
+			res := g.gather(path)
+			if !reflect.DeepEqual(test.exp, res) {
+				t.Errorf("not equel: exp=%#v out=%#v", test.exp, res)
+			}
+		}
+	}
+
+	t.Run("none error", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		tests := []Tests{
+			{
+				in: "TODO: hi",
+				exp: &gatherRes{
+					path:    path,
+					matches: []*match{{num: 1, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: hi"}},
+					err:     nil,
+				},
+			},
+			{
+				in: "TODO: hello\nTODO: world\n",
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{num: 1, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: hello"},
+						{num: 2, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: world"},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	t.Run("use trim", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.Trim = true
+		tests := []Tests{
+			{
+				in: "TODO: hi",
+				exp: &gatherRes{
+					path:    path,
+					matches: []*match{{num: 1, col: 1, tag: "TODO: ", text: "hi"}},
+					err:     nil,
+				},
+			},
+			{
+				in: "TODO: hello\nTODO: world\n",
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{num: 1, col: 1, tag: "TODO: ", text: "hello"},
+						{num: 2, col: 1, tag: "TODO: ", text: "world"},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	t.Run("use add", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.Add = 1
+		tests := []Tests{
+			{
+				in: "TODO: hi\nnext 1 line\nnext 2 line",
+				exp: &gatherRes{
+					path:    path,
+					matches: []*match{{num: 1, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: hi", adds: []string{"next 1 line"}}},
+					err:     nil,
+				},
+			},
+			{
+				in: "TODO: hello\nTODO: world\n",
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{num: 1, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: hello"},
+						{num: 2, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: world"},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	t.Run("use before", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.Before = 2
+		g.Add = 1
+		tests := []Tests{
+			{
+				in: "1\n2\n3\nTODO: a\n5\n6\nTODO: b\n8\n",
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{num: 4, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: a", befores: []string{"2", "3"}, adds: []string{"5"}},
+						{num: 7, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: b", befores: []string{"6"}, adds: []string{"8"}},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	t.Run("multiple words", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.Words = []string{"TODO: ", "FIXME: "}
+		tests := []Tests{
+			{
+				in: "TODO: hello\nFIXME: world\nFIXME: TODO: first\n",
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{num: 1, col: 1, tagAt: 1, tag: "TODO: ", text: "TODO: hello"},
+						{num: 2, col: 1, tagAt: 1, tag: "FIXME: ", text: "FIXME: world"},
+						{num: 3, col: 1, tagAt: 1, tag: "FIXME: ", text: "FIXME: TODO: first"},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	/// expected return errors
+
+	t.Run("err file not exists", func(t *testing.T) {
+		notexists := path + "notexits"
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		res := g.gather(notexists)
+		if os.IsNotExist(res.err) {
+			return
+		}
+		t.Errorf("expected error is %#v but out %#v", os.ErrNotExist, res.err)
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.MaxRune = 16
+		g.Add = 1
+		long := strings.Repeat("a", 40) + "TODO: hello" + strings.Repeat("b", 40)
+		tests := []Tests{
+			{
+				in: TooLongLine,
+				exp: &gatherRes{
+					path:    path,
+					matches: nil,
+					err:     nil,
+				},
+			},
+			{
+				in: long + "\n" + TooLongLine,
+				exp: &gatherRes{
+					path: path,
+					matches: []*match{
+						{
+							num:   1,
+							col:   41,
+							tagAt: 8,
+							tag:   "TODO: ",
+							text:  "...aaaaTODO: hellob...",
+							adds:  []string{TooLongLine[:16] + "..."},
+						},
+					},
+					err: nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+
+	t.Run("long line with trim", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.MaxRune = 4
+		g.Trim = true
+		tests := []Tests{
+			{
+				in: "xxTODO: hello",
+				exp: &gatherRes{
+					path:    path,
+					matches: []*match{{num: 1, col: 3, tag: "TODO: ", text: "hell..."}},
+					err:     nil,
+				},
+			},
+		}
+		verify(t, g, tests)
+	})
+}
+
+func Test_excerptIndex(t *testing.T) {
+	tests := []struct {
+		s      string
+		i, max int
+		exp    string
+		expAt  int
+	}{
+		{s: "abc TODO: x", i: 4, max: 0, exp: "abc TODO: x", expAt: 4},
+		{s: "0123456789TODO: x", i: 10, max: 8, exp: "...89TODO: ...", expAt: 5},
+		{s: "あいうえおTODO: x", i: 15, max: 8, exp: "...えおTODO: ...", expAt: 9},
+	}
+	for _, test := range tests {
+		out, at := excerptIndex(test.s, test.i, test.max)
+		if out != test.exp || at != test.expAt {
+			t.Errorf("%+v: out=%q at=%d", test, out, at)
+		}
+	}
+}
+
+func Test_gatherResErr(t *testing.T) {
+	tests := []struct {
+		gr  *gatherRes
+		exp error
+	}{
+		{
+			gr: &gatherRes{
+				path:    "path",
+				matches: nil,
+				err:     nil,
+			},
+			exp: nil,
+		},
+		{
+			gr: &gatherRes{
+				path:    "path",
+				matches: nil,
+				err:     os.ErrPermission,
+			},
+			exp: os.ErrPermission,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.gr.Err()
+		if !reflect.DeepEqual(test.exp, err) {
+			t.Errorf("exp=%#v but out=%#v", test.exp, err)
+		}
+	}
+}
+
+func TestFwrite(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	tests := []struct {
+		gr      *gatherRes
+		exp     string
+		wanterr bool
+	}{
+		{
+			gr: &gatherRes{
+				path:    "path",
+				matches: []*match{{num: 1, col: 1, tag: "TODO: ", text: "hi"}},
+				err:     nil,
+			},
+			exp:     "path\n" + "L1:hi\n\n",
+			wanterr: false,
+		},
+		{
+			gr: &gatherRes{
+				path:    "path",
+				matches: nil,
+				err:     nil,
+			},
+			exp:     "",
+			wanterr: false,
+		},
+		{
+			gr: &gatherRes{
+				path:    "path",
+				matches: nil,
+				err:     os.ErrPermission,
+			},
+			exp:     "",
+			wanterr: true,
+		},
+	}
+
+	for _, test := range tests {
+		buf.Reset()
+		err := test.gr.Fwrite(buf)
+		if test.wanterr && err == nil {
+			t.Error("expected error but nil")
+		}
+		if test.exp != buf.String() {
+			t.Errorf("exp=%#v but out=%#v", test.exp, buf.String())
+		}
+	}
+}
+
+func TestFwriteContext(t *testing.T) {
+	gr := &gatherRes{
+		path: "path",
+		matches: []*match{
+			{num: 2, text: "TODO: a", befores: []string{"1"}, adds: []string{"3"}},
+			// merged with previous
+			{num: 5, text: "TODO: b", befores: []string{"4"}, adds: []string{"6"}},
+			// separated
+			{num: 10, text: "TODO: c", befores: []string{"9"}},
+		},
+	}
+	exp := "path\n" +
+		" 1:1\n" + "L2:TODO: a\n" + " 3:3\n" +
+		" 4:4\n" + "L5:TODO: b\n" + " 6:6\n" +
+		"--\n" +
+		" 9:9\n" + "L10:TODO: c\n\n"
+	buf := bytes.NewBufferString("")
+	if err := gr.Fwrite(buf); err != nil {
+		t.Fatal(err)
+	}
+	if exp != buf.String() {
+		t.Errorf("exp=%#v\nout=%#v", exp, buf.String())
+	}
+}
+
+func TestGroupAndTotal(t *testing.T) {
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	buf := bytes.NewBufferString("")
+	g.W = buf
+	g.Words = []string{"TODO: ", "FIXME: ", "BUG: "}
+	g.Group = true
+
+	results := []*gatherRes{
+		{
+			path: "b",
+			matches: []*match{
+				{num: 1, tag: "FIXME: ", text: "FIXME: b1"},
+				{num: 2, tag: "TODO: ", text: "TODO: b2"},
+			},
+		},
+		{
+			path:    "a",
+			matches: []*match{{num: 3, tag: "TODO: ", text: "TODO: a3"}},
+		},
+	}
+	for _, gr := range results {
+		if err := g.write(gr); err != nil {
+			t.Fatal(err)
+		}
+		g.count(gr)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected hold until flush but out=%#v", buf.String())
+	}
+	if err := g.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.PrintTotal(); err != nil {
+		t.Fatal(err)
+	}
+	exp := "[TODO: ]\n\n" +
+		"a\nL3:TODO: a3\n\n" +
+		"b\nL2:TODO: b2\n\n" +
+		"[FIXME: ]\n\n" +
+		"b\nL1:FIXME: b1\n\n" +
+		"files 2\nlines 3\nerrors 0\n" +
+		"lines \"TODO: \" 2\n" +
+		"lines \"FIXME: \" 1\n" +
+		"lines \"BUG: \" 0\n"
+	if exp != buf.String() {
+		t.Errorf("exp=%#v\nout=%#v", exp, buf.String())
+	}
+}
+
+func Test_isTarget(t *testing.T) {
+	type Tests struct {
+		path   string
+		target bool
+	}
+	f := func(t *testing.T, g *Gotcha, tests []Tests) {
+		for _, test := range tests {
+			b := g.isTarget(test.path)
+			if test.target != b {
+				t.Errorf("path=%#v want bool is %#v but %#v", test.path, test.target, b)
+			}
+		}
+	}
+	t.Run("use ignore base", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.IgnoreBasesMap["ignore"] = true
+		tests := []Tests{
+			{
+				path:   "ignore",
+				target: false,
+			},
+			{
+				path:   "target.txt",
+				target: true,
+			},
+		}
+		f(t, g, tests)
+	})
+
+	t.Run("ignore type", func(t *testing.T) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		tests := []Tests{
+			{
+				path:   "ignore.bz",
+				target: false,
+			},
+			{
+				path:   "target.txt",
+				target: true,
+			},
+		}
+		f(t, g, tests)
+	})
+}
+
+// TODO: impl
+func TestAsyncWorkGo(t *testing.T) {
+	root := filepath.Join(TestRoot, "async_work_go")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	t.Run("valid exit", func(t *testing.T) {
+		r := filepath.Join(root, "valid_exit")
+		if err := os.MkdirAll(r, 0777); err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(r)
+
+		path := filepath.Join(r, "hello.txt")
+		contents := "// TODO: hello"
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(path)
+
+		exp := path + "\n" + "L1:" + contents + "\n\n"
+		buf := bytes.NewBufferString("")
+		errbuf := bytes.NewBufferString("")
+		g := NewGotcha()
+		g.Log.SetOutput(errbuf)
+		g.W = buf
+
+		if res := g.WorkGo(context.Background(), root, 0); len(res.Errors) != 0 {
+			t.Fatalf("expected no errors but %v: errbuf:%s", res.Errors, errbuf.String())
+		}
+		if exp != buf.String() {
+			t.Fatalf("exp:%s but out:%s", exp, buf.String())
+		}
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		r := filepath.Join(root, "long_line")
+		if err := os.MkdirAll(r, 0777); err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(r)
+
+		path := filepath.Join(r, "toolong.txt")
+		contents := TooLongLine + "\n" + "TODO: " + TooLongLine
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := bytes.NewBufferString("")
+		errbuf := bytes.NewBufferString("")
+		g := NewGotcha()
+		g.Log.SetOutput(errbuf)
+		g.W = buf
+
+		if res := g.WorkGo(context.Background(), r, 0); len(res.Errors) != 0 {
+			t.Fatalf("expected no errors but %v: errbuf:%s", res.Errors, errbuf.String())
+		}
+		exp := path + "\n" + "L2:" + ("TODO: " + TooLongLine)[:g.MaxRune] + "...\n\n"
+		if exp != buf.String() {
+			t.Fatalf("exp=%#v but out=%#v", exp, buf.String())
+		}
+	})
+
+	t.Run("ordered", func(t *testing.T) {
+		r := filepath.Join(root, "ordered")
+		defer os.RemoveAll(r)
+		// lexical walk order, files and directories are interleaved by name
+		names := []string{"a.txt", "b/a.txt", "b/c/a.txt", "b/d.txt", "c.txt", "d/a.txt", "e.txt"}
+		var exp string
+		for _, name := range names {
+			path := filepath.Join(r, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, []byte("TODO: "+name), 0666); err != nil {
+				t.Fatal(err)
+			}
+			exp += path + "\n" + "L1:TODO: " + name + "\n\n"
+		}
+
+		for i := 0; i != 10; i++ {
+			buf := bytes.NewBufferString("")
+			errbuf := bytes.NewBufferString("")
+			g := NewGotcha()
+			g.Log.SetOutput(errbuf)
+			g.W = buf
+			g.Ordered = true
+			if res := g.WorkGo(context.Background(), r, 4); len(res.Errors) != 0 {
+				t.Fatalf("expected no errors but %v: errbuf:%s", res.Errors, errbuf.String())
+			}
+			if exp != buf.String() {
+				t.Fatalf("exp=%#v but out=%#v", exp, buf.String())
+			}
+		}
+
+		buf := bytes.NewBufferString("")
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.W = buf
+		if res := g.SyncWorkGo(context.Background(), r); len(res.Errors) != 0 || exp != buf.String() {
+			t.Fatalf("expected same as SyncWorkGo but errors=%v out=%#v", res.Errors, buf.String())
+		}
+	})
+
+	t.Run("result", func(t *testing.T) {
+		r := filepath.Join(root, "result")
+		if err := os.MkdirAll(filepath.Join(r, "dir"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(r)
+		files := map[string]string{
+			"a.txt":     "TODO: a\nFIXME: a\n",
+			"dir/b.txt": "TODO: b\nTODO: b\n",
+			"c.txt":     "none\n",
+		}
+		for name, contents := range files {
+			if err := ioutil.WriteFile(filepath.Join(r, filepath.FromSlash(name)), []byte(contents), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		missing := filepath.Join(r, "missing")
+
+		for _, ordered := range []bool{true, false} {
+			g := NewGotcha()
+			g.Log.SetOutput(ioutil.Discard)
+			g.W = ioutil.Discard
+			g.Words = []string{"TODO: ", "FIXME: "}
+			g.Ordered = ordered
+			res := g.WorkGo(context.Background(), r, 0)
+			exp := &Result{Files: 2, Lines: 4, Tags: map[string]uint{"TODO: ": 3, "FIXME: ": 1}}
+			if !reflect.DeepEqual(exp, res) {
+				t.Errorf("ordered=%v exp=%#v out=%#v", ordered, exp, res)
+			}
+
+			res = g.WorkGo(context.Background(), missing, 0)
+			if len(res.Errors) != 1 || !os.IsNotExist(res.Errors[0]) {
+				t.Errorf("ordered=%v expected not exist error but %v", ordered, res.Errors)
+			}
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		g.W = ioutil.Discard
+		res := g.WorkGo(ctx, root, 0)
+		if len(res.Errors) != 1 || res.Errors[0] != context.Canceled {
+			t.Errorf("expected canceled but %v", res.Errors)
+		}
+		res = NewGotcha().SyncWorkGo(ctx, root)
+		if len(res.Errors) != 1 || res.Errors[0] != context.Canceled {
+			t.Errorf("expected canceled but %v", res.Errors)
+		}
+	})
+}
+
+func TestSyncWorkGo(t *testing.T) {
+	// make root
+	root := filepath.Join(TestRoot, "sync_work_go")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// make directory
+	dir := filepath.Join(root, "dir")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	// make file
+	file := filepath.Join(dir, "hello.txt")
+	if err := ioutil.WriteFile(file, []byte("TODO: hello"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("gotcha", func(t *testing.T) {
+		g := NewGotcha()
+		errbuf := bytes.NewBufferString("")
+		g.Log.SetOutput(errbuf)
+		buf := bytes.NewBufferString("")
+		g.W = buf
+		if res := g.SyncWorkGo(context.Background(), root); len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+		exp := file + "\n" + "L1:TODO: hello\n\n"
+		if buf.String() != exp {
+			t.Fatalf("exp=%#v but out=%#v", exp, buf.String())
+		}
+	})
+
+	t.Run("ignore dir", func(t *testing.T) {
+		g := NewGotcha()
+		errbuf := bytes.NewBufferString("")
+		g.Log.SetOutput(errbuf)
+		buf := bytes.NewBufferString("")
+		g.W = buf
+		g.IgnoreDirsMap[filepath.Base(dir)] = true
+		if res := g.SyncWorkGo(context.Background(), root); len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+		exp := ""
+		if buf.String() != exp {
+			t.Fatalf("exp=%#v but out=%#v", exp, buf.String())
+		}
+	})
+
+	t.Run("too long line", func(t *testing.T) {
+		g := NewGotcha()
+		errbuf := bytes.NewBufferString("")
+		g.Log.SetOutput(errbuf)
+		buf := bytes.NewBufferString("")
+		g.W = buf
+		g.IgnoreDirsMap[filepath.Base(dir)] = true
+
+		tooLongFile := filepath.Join(root, "too_long")
+		ioutil.WriteFile(tooLongFile, []byte(TooLongLine+"TODO: hello"), 0666)
+		if res := g.SyncWorkGo(context.Background(), root); len(res.Errors) != 0 {
+			t.Fatal("expected no errors but", res.Errors, errbuf)
+		}
+		exp := tooLongFile + "\n" + "L1:..." + (TooLongLine + "TODO: hello")[len(TooLongLine)+len("TODO: hello")-g.MaxRune:] + "\n\n"
+		if buf.String() != exp {
+			t.Fatalf("exp=%#v but out=%#v", exp, buf.String())
+		}
+	})
+}
+
+func TestWorkGoReuse(t *testing.T) {
+	root := filepath.Join(TestRoot, "work_go_reuse")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("TODO: a\nTODO: b\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.W = ioutil.Discard
+	g.Keep = true
+	for i, work := range []func() *Result{
+		func() *Result { return g.WorkGo(context.Background(), root, 0) },
+		func() *Result { return g.SyncWorkGo(context.Background(), root) },
+		func() *Result { return g.Search(context.Background(), root, func(*Match) error { return nil }) },
+		func() *Result { return g.WorkGo(context.Background(), filepath.Join(root, "missing"), 0) },
+	} {
+		res := work()
+		exp := &Result{Files: 1, Lines: 2, Tags: map[string]uint{"TODO: ": 2}}
+		if i == 3 {
+			exp = &Result{Tags: map[string]uint{}}
+		}
+		if res.Files != exp.Files || res.Lines != exp.Lines || !reflect.DeepEqual(exp.Tags, res.Tags) || len(res.Matches) != int(exp.Lines) {
+			t.Errorf("%d: unexpected result: %#v", i, res)
+		}
+		if len(res.Errors) != i/3 {
+			t.Errorf("%d: unexpected errors: %v", i, res.Errors)
+		}
+	}
+}
+
+func Test_dedupeRoots(t *testing.T) {
+	tests := []struct {
+		in, exp []string
+	}{
+		{in: []string{"a"}, exp: []string{"a"}},
+		{in: []string{"a", "b"}, exp: []string{"a", "b"}},
+		{in: []string{"a/x.go", "a", "b"}, exp: []string{"a", "b"}},
+		{in: []string{"a", "./a/", "a/b/c"}, exp: []string{"a"}},
+		{in: []string{"ab", "a"}, exp: []string{"ab", "a"}},
+		{in: []string{"/", "/a"}, exp: []string{"/"}},
+	}
+	for _, test := range tests {
+		if out := dedupeRoots(test.in); !reflect.DeepEqual(test.exp, out) {
+			t.Errorf("in=%q exp=%q out=%q", test.in, test.exp, out)
+		}
+	}
+}
+
+func TestWorkGoRoots(t *testing.T) {
+	root := filepath.Join(TestRoot, "work_go_roots")
+	for _, dir := range []string{"a", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.RemoveAll(root)
+	var (
+		a = filepath.Join(root, "a", "x.txt")
+		b = filepath.Join(root, "b", "y.txt")
+	)
+	for _, path := range []string{a, b} {
+		if err := ioutil.WriteFile(path, []byte("TODO: "+filepath.Base(path)), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is walked once
+	roots := []string{b, filepath.Join(root, "a"), a}
+	exp := b + "\nL1:TODO: y.txt\n\n" + a + "\nL1:TODO: x.txt\n\n"
+	for _, work := range []string{"async", "sync"} {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		buf := new(bytes.Buffer)
+		g.W = buf
+		var res *Result
+		if work == "async" {
+			res = g.WorkGoRoots(context.Background(), roots, 0)
+		} else {
+			res = g.SyncWorkGoRoots(context.Background(), roots)
+		}
+		if len(res.Errors) != 0 || res.Lines != 2 {
+			t.Errorf("%s: unexpected result: %#v", work, res)
+		}
+		if buf.String() != exp {
+			t.Errorf("%s: exp=%#v out=%#v", work, exp, buf.String())
+		}
+	}
+}
+
+func TestWalkFollow(t *testing.T) {
+	root := filepath.Join(TestRoot, "walk_follow")
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "a", "x.txt"), []byte("TODO: x"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"link":      "a",
+		"f.txt":     filepath.Join("a", "x.txt"),
+		"a/loop":    "..",
+		"dangling":  "missing",
+		"a/sibling": filepath.Join("..", "link"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip(err)
+		}
+	}
+	tests := []struct {
+		follow   bool
+		oneFS    bool
+		maxDepth uint
+		exp      []string
+	}{
+		{exp: []string{"a/x.txt"}},
+		{follow: true, exp: []string{"a/x.txt", "f.txt", "link/x.txt"}},
+		{follow: true, oneFS: true, exp: []string{"a/x.txt", "f.txt", "link/x.txt"}},
+		{follow: true, maxDepth: 1, exp: []string{"f.txt"}},
+		{maxDepth: 2, exp: []string{"a/x.txt"}},
+	}
+	for _, test := range tests {
+		for _, work := range []string{"async", "sync"} {
+			g := NewGotcha()
+			g.Log.SetOutput(ioutil.Discard)
+			g.Follow = test.follow
+			g.OneFileSystem = test.oneFS
+			g.MaxDepth = test.maxDepth
+			var out []string
+			consume := func(gr *gatherRes) error {
+				rel, err := filepath.Rel(root, gr.path)
+				if err != nil {
+					return err
+				}
+				out = append(out, filepath.ToSlash(rel))
+				return nil
+			}
+			var err error
+			if work == "async" {
+				g.Ordered = true
+				err = g.workAsync(context.Background(), []string{root}, 0, consume)
+			} else {
+				err = g.workSync(context.Background(), []string{root}, consume)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(test.exp, out) {
+				t.Errorf("%s: follow=%v maxDepth=%d exp=%q out=%q", work, test.follow, test.maxDepth, test.exp, out)
+			}
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	root := filepath.Join(TestRoot, "search")
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := map[string]string{
+		"a.txt":     "TODO: a\nafter\n",
+		"dir/b.txt": "x TODO: b\n",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	buf := bytes.NewBufferString("")
+	g.W = buf
+	g.Add = 1
+	var out []*Match
+	res := g.Search(context.Background(), root, func(m *Match) error {
+		out = append(out, m)
+		return nil
+	})
+	if len(res.Errors) != 0 || res.Files != 2 || res.Lines != 2 {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+	exp := []*Match{
+		{Path: filepath.Join(root, "a.txt"), Line: 1, Col: 1, Tag: "TODO: ", Text: "TODO: a", tagAt: 1, Context: []string{"after"}},
+		{Path: filepath.Join(root, "dir", "b.txt"), Line: 1, Col: 3, Tag: "TODO: ", Text: "x TODO: b", tagAt: 3},
+	}
+	if !reflect.DeepEqual(exp, out) {
+		t.Errorf("exp=%#v out=%#v", exp, out)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output to W but %q", buf)
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		stop := errors.New("stop")
+		n := 0
+		res := NewGotcha().Search(context.Background(), root, func(m *Match) error {
+			n++
+			return stop
+		})
+		if n != 1 || len(res.Errors) != 1 || res.Errors[0] != stop {
+			t.Errorf("expected stop by first match but n=%d errors=%v", n, res.Errors)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package gotcha
+
+import (
+	"context"
+	"sync"
+)
+
+// group is goroutines of a work like errgroup.Group
+// the first error cancel the context of the group
+type group struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+// withGroup return new group and derived context
+func withGroup(ctx context.Context) (*group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &group{cancel: cancel}, ctx
+}
+
+// Go run f in new goroutine
+func (gr *group) Go(f func() error) {
+	gr.wg.Add(1)
+	go func() {
+		defer gr.wg.Done()
+		if err := f(); err != nil {
+			gr.once.Do(func() {
+				gr.err = err
+				gr.cancel()
+			})
+		}
+	}()
+}
+
+// Wait block until all goroutines are returned, return the first error
+func (gr *group) Wait() error {
+	gr.wg.Wait()
+	gr.cancel()
+	return gr.err
+}
@@ -0,0 +1,393 @@
+package gotcha
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryFormats available output formats of history
+var HistoryFormats = []string{"csv", "json"}
+
+// Counts is number of matches of a tree
+type Counts struct {
+	// number of files of have matches
+	Files uint `json:"files"`
+	// number of matches and break down by tag
+	Lines uint            `json:"lines"`
+	Tags  map[string]uint `json:"tags"`
+}
+
+func (c *Counts) add(o *Counts) {
+	c.Files += o.Files
+	c.Lines += o.Lines
+	for tag, n := range o.Tags {
+		c.Tags[tag] += n
+	}
+}
+
+// Point is Counts of a commit
+type Point struct {
+	Commit string    `json:"commit"`
+	Tree   string    `json:"tree"`
+	Date   time.Time `json:"date"`
+	Counts
+}
+
+// HistoryCache is Counts by tree hash, trees are scanned once
+type HistoryCache struct {
+	// fingerprint of options, Trees of other options are dropped
+	Key   string             `json:"key"`
+	Trees map[string]*Counts `json:"trees"`
+}
+
+// LoadHistoryCache read HistoryCache from path, return empty if not exist
+func LoadHistoryCache(path string) (*HistoryCache, error) {
+	cache := &HistoryCache{}
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(b, cache); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if cache.Trees == nil {
+		cache.Trees = make(map[string]*Counts)
+	}
+	return cache, nil
+}
+
+// Save write cache to path
+func (cache *HistoryCache) Save(path string) error {
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return writeAtomic(path, b)
+}
+
+// HistoryCachePath return default path of HistoryCache in git directory of dir
+func HistoryCachePath(dir string) (string, error) {
+	out, err := gitOutput(context.Background(), dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), Name, "history.json"), nil
+}
+
+// gitOutput return stdout of git on dir
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// historyKey return fingerprint of options for HistoryCache
+// prefix is path of dir in the repository
+func (g *Gotcha) historyKey(prefix string) string {
+	b, _ := json.Marshal(struct {
+		Version      string
+		Prefix       string
+		Words        []string
+		Types        map[string]bool
+		IgnoreDirs   map[string]bool
+		IgnoreBases  map[string]bool
+		IgnoreTypes  map[string]bool
+		CommentsOnly bool
+		Binary       string
+		Encoding     string
+		Encodings    map[string]string
+	}{
+		Version, prefix, g.Words, g.TypesMap, g.IgnoreDirsMap, g.IgnoreBasesMap, g.IgnoreTypesMap,
+		g.CommentsOnly, g.Binary, g.Encoding, g.EncodingsMap,
+	})
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// History count matches of trees of each commits of rev in git repository of dir
+// commits are listed by git rev-list in oldest first, trees are read without
+// checkout and limited to dir, cache is used and updated if not nil
+func (g *Gotcha) History(ctx context.Context, dir, rev string, cache *HistoryCache) ([]*Point, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	out, err := gitOutput(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	if key := g.historyKey(strings.TrimSpace(string(out))); cache != nil && cache.Key != key {
+		cache.Key = key
+		cache.Trees = make(map[string]*Counts)
+	}
+	out, err = gitOutput(ctx, dir, "rev-list", "--reverse", "--format=%T %ct", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	points, err := parseRevList(out)
+	if err != nil {
+		return nil, err
+	}
+	// Counts of a file by blob and extension, trees share most of blobs
+	blobs := make(map[string]*Counts)
+	for _, p := range points {
+		if cache != nil {
+			if c, ok := cache.Trees[p.Tree]; ok {
+				p.Counts = *c
+				continue
+			}
+		}
+		c, err := g.scanTree(ctx, dir, p.Tree, blobs)
+		if err != nil {
+			return nil, err
+		}
+		p.Counts = *c
+		if cache != nil {
+			cache.Trees[p.Tree] = c
+		}
+	}
+	return points, nil
+}
+
+// parseRevList parse output of git rev-list --format="%T %ct"
+func parseRevList(b []byte) ([]*Point, error) {
+	var (
+		points []*Point
+		cur    *Point
+		sc     = bufio.NewScanner(bytes.NewReader(b))
+	)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "commit ") {
+			cur = &Point{Commit: strings.TrimPrefix(line, "commit ")}
+			points = append(points, cur)
+			continue
+		}
+		fields := strings.Fields(line)
+		if cur == nil || len(fields) != 2 {
+			return nil, fmt.Errorf("git rev-list: unexpected line: %q", line)
+		}
+		sec, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git rev-list: invalid date: %q", line)
+		}
+		cur.Tree, cur.Date = fields[0], time.Unix(sec, 0).UTC()
+	}
+	return points, sc.Err()
+}
+
+// treeEntry is a blob of tree
+type treeEntry struct {
+	blob string
+	path string
+}
+
+// lsTree return target blobs of tree in dir by git ls-tree
+func (g *Gotcha) lsTree(ctx context.Context, dir, tree string) ([]treeEntry, error) {
+	out, err := gitOutput(ctx, dir, "ls-tree", "-r", "-z", tree)
+	if err != nil {
+		return nil, err
+	}
+	var entries []treeEntry
+	for _, rec := range bytes.Split(out, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <file>
+		tab := bytes.IndexByte(rec, '\t')
+		if tab == -1 {
+			continue
+		}
+		fields := strings.Fields(string(rec[:tab]))
+		name := string(rec[tab+1:])
+		if len(fields) != 3 || fields[1] != "blob" || fields[0] == "120000" || !g.isTreeTarget(name) {
+			continue
+		}
+		entries = append(entries, treeEntry{blob: fields[2], path: name})
+	}
+	return entries, nil
+}
+
+// isTreeTarget reports whether name of path in tree is target as files of walk
+func (g *Gotcha) isTreeTarget(name string) bool {
+	dirs := strings.Split(path.Dir(name), "/")
+	for _, dir := range dirs {
+		if g.IgnoreDirsMap[dir] {
+			return false
+		}
+	}
+	return g.isTarget(path.Base(name))
+}
+
+// scanTree count matches of tree, blobs are cache of Counts of files
+func (g *Gotcha) scanTree(ctx context.Context, dir, tree string, blobs map[string]*Counts) (*Counts, error) {
+	entries, err := g.lsTree(ctx, dir, tree)
+	if err != nil {
+		return nil, err
+	}
+	key := func(e treeEntry) string { return e.blob + "\x00" + path.Ext(e.path) }
+	// paths to gather by blob
+	pending := make(map[string][]string)
+	var order []string
+	for _, e := range entries {
+		k := key(e)
+		if _, ok := blobs[k]; ok {
+			continue
+		}
+		if _, ok := pending[e.blob]; !ok {
+			order = append(order, e.blob)
+		}
+		blobs[k] = nil
+		pending[e.blob] = append(pending[e.blob], e.path)
+	}
+	err = catBlobs(ctx, dir, order, func(blob string, b []byte) error {
+		for _, name := range pending[blob] {
+			gr := g.gatherReader(name, name, bytes.NewReader(b))
+			if gr.err != nil {
+				g.Log.Printf("%s: %s: %v\n\n", tree, name, gr.err)
+			}
+			c := &Counts{Tags: make(map[string]uint)}
+			if len(gr.matches) != 0 {
+				c.Files = 1
+			}
+			c.Lines = uint(len(gr.matches))
+			for _, m := range gr.matches {
+				c.Tags[m.tag]++
+			}
+			blobs[blob+"\x00"+path.Ext(name)] = c
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := &Counts{Tags: make(map[string]uint)}
+	for _, e := range entries {
+		if c := blobs[key(e)]; c != nil {
+			counts.add(c)
+		}
+	}
+	return counts, nil
+}
+
+// catBlobs call fn with contents of each blobs by git cat-file --batch
+func catBlobs(ctx context.Context, dir string, blobs []string, fn func(blob string, b []byte) error) (err error) {
+	if len(blobs) == 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = dir
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git cat-file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return
+		}
+		if werr := cmd.Wait(); werr != nil {
+			err = fmt.Errorf("git cat-file: %v: %s", werr, strings.TrimSpace(stderr.String()))
+		}
+	}()
+	go func() {
+		defer stdin.Close()
+		for _, blob := range blobs {
+			if _, err := io.WriteString(stdin, blob+"\n"); err != nil {
+				return
+			}
+		}
+	}()
+
+	br := bufio.NewReader(stdout)
+	for range blobs {
+		// <object> SP <type> SP <size> LF <contents> LF
+		header, err := br.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("git cat-file: %v", err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("git cat-file: unexpected header: %q", header)
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("git cat-file: unexpected header: %q", header)
+		}
+		b := make([]byte, size+1)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return fmt.Errorf("git cat-file: %v", err)
+		}
+		if err := fn(fields[0], b[:size]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteHistory write points to w by format of HistoryFormats
+// columns of csv are commit, date, files, lines and each Words
+func (g *Gotcha) WriteHistory(w io.Writer, format string, points []*Point) error {
+	switch format {
+	case "", "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"commit", "date", "files", "lines"}, g.Words...))
+		for _, p := range points {
+			rec := []string{
+				p.Commit,
+				p.Date.Format(time.RFC3339),
+				strconv.FormatUint(uint64(p.Files), 10),
+				strconv.FormatUint(uint64(p.Lines), 10),
+			}
+			for _, tag := range g.Words {
+				rec = append(rec, strconv.FormatUint(uint64(p.Tags[tag]), 10))
+			}
+			cw.Write(rec)
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		if points == nil {
+			points = []*Point{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(points)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
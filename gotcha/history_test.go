@@ -0,0 +1,137 @@
+package gotcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	root, err := filepath.Abs(filepath.Join(TestRoot, "history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	// git return resolved path
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(date string, files map[string]string) {
+		for name, s := range files {
+			if err := ioutil.WriteFile(filepath.Join(root, name), []byte(s), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		gitRun(t, root, date, "add", "-A")
+		gitRun(t, root, date, "commit", "-q", "-m", date)
+	}
+	gitRun(t, root, "", "init", "-q")
+	commit("2001-01-01T00:00:00Z", map[string]string{"a.txt": "TODO: a\n", "image.png": "TODO: ignored"})
+	commit("2001-01-02T00:00:00Z", map[string]string{"src/b.go": "// TODO: b\n// FIXME: b\n", "src/c.go": "// TODO: b\n// FIXME: b\n"})
+	commit("2001-01-03T00:00:00Z", map[string]string{"a.txt": "done\n"})
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.Words = []string{"TODO: ", "FIXME: "}
+	cache := &HistoryCache{}
+	points, err := g.History(context.Background(), root, "HEAD", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Counts{
+		{Files: 1, Lines: 1, Tags: map[string]uint{"TODO: ": 1}},
+		{Files: 3, Lines: 5, Tags: map[string]uint{"TODO: ": 3, "FIXME: ": 2}},
+		{Files: 2, Lines: 4, Tags: map[string]uint{"TODO: ": 2, "FIXME: ": 2}},
+	}
+	if len(points) != len(exp) {
+		t.Fatalf("unexpected points: %#v", points)
+	}
+	for i, p := range points {
+		if !reflect.DeepEqual(exp[i], p.Counts) {
+			t.Errorf("%d: exp=%#v out=%#v", i, exp[i], p.Counts)
+		}
+		if p.Date.Day() != i+1 || len(p.Commit) != 40 || len(p.Tree) != 40 {
+			t.Errorf("%d: unexpected point: %#v", i, p)
+		}
+	}
+	if len(cache.Trees) != 3 || cache.Key == "" {
+		t.Errorf("unexpected cache: %#v", cache)
+	}
+
+	t.Run("subdir", func(t *testing.T) {
+		points, err := g.History(context.Background(), filepath.Join(root, "src"), "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 3 || points[0].Lines != 0 || points[2].Lines != 4 {
+			t.Errorf("unexpected points: %#v", points)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		path := filepath.Join(root, ".git", Name, "history.json")
+		if out, err := HistoryCachePath(root); err != nil || out != path {
+			t.Fatalf("exp=%s out=%s err=%v", path, out, err)
+		}
+		// cached Counts are used as is
+		cache.Trees[points[0].Tree] = &Counts{Lines: 99}
+		if err := cache.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadHistoryCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := g.History(context.Background(), root, "", loaded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0].Lines != 99 || out[1].Lines != 5 {
+			t.Errorf("expected cached counts: %#v", out)
+		}
+
+		// other options drop cache
+		g.Words = []string{"TODO: "}
+		out, err = g.History(context.Background(), root, "", loaded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0].Lines != 1 || out[1].Lines != 3 {
+			t.Errorf("expected rescan: %#v", out)
+		}
+		g.Words = []string{"TODO: ", "FIXME: "}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		if err := g.WriteHistory(buf, "csv", points); err != nil {
+			t.Fatal(err)
+		}
+		recs, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expcsv := [][]string{
+			{"commit", "date", "files", "lines", "TODO: ", "FIXME: "},
+			{points[0].Commit, "2001-01-01T00:00:00Z", "1", "1", "1", "0"},
+			{points[1].Commit, "2001-01-02T00:00:00Z", "3", "5", "3", "2"},
+			{points[2].Commit, "2001-01-03T00:00:00Z", "2", "4", "2", "2"},
+		}
+		if !reflect.DeepEqual(expcsv, recs) {
+			t.Errorf("exp=%q out=%q", expcsv, recs)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package gotcha
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// htmlWriter write self contained HTML report on flush
+// matches are shown in tree of directories with counts by tag
+type htmlWriter struct {
+	w       io.Writer
+	results []*gatherRes
+}
+
+func (hw *htmlWriter) write(gr *gatherRes) error {
+	if len(gr.matches) != 0 {
+		hw.results = append(hw.results, gr)
+	}
+	return nil
+}
+
+func (hw *htmlWriter) group(tag string) error { return nil }
+
+func (hw *htmlWriter) flush() error {
+	report := &htmlReport{
+		Name:      Name,
+		Version:   Version,
+		Generated: time.Now().Format(time.RFC3339),
+		Root:      newHTMLTree(hw.results),
+	}
+	var (
+		tags    = make(map[string]int)
+		authors = make(map[string]int)
+		// results are split by tag with Group or by match with Sort
+		files = make(map[string]bool)
+	)
+	for _, gr := range hw.results {
+		files[gr.path] = true
+		for _, m := range gr.matches {
+			report.Matches++
+			tags[m.tag]++
+			if m.blame != nil {
+				authors[m.blame.Author]++
+			}
+		}
+	}
+	report.Files = len(files)
+	report.Tags = htmlCounts(tags)
+	report.Authors = htmlCounts(authors)
+	// most first
+	sort.SliceStable(report.Authors, func(i, j int) bool { return report.Authors[i].N > report.Authors[j].N })
+	return htmlTemplate.Execute(hw.w, report)
+}
+
+type htmlReport struct {
+	Name      string
+	Version   string
+	Generated string
+	Files     int
+	Matches   int
+	Tags      []htmlCount
+	Authors   []htmlCount
+	Root      *htmlDir
+}
+
+type htmlCount struct {
+	Name string
+	N    int
+}
+
+// htmlCounts return counts of sorted by name
+func htmlCounts(m map[string]int) []htmlCount {
+	var counts []htmlCount
+	for name, n := range m {
+		counts = append(counts, htmlCount{Name: name, N: n})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Name < counts[j].Name })
+	return counts
+}
+
+// htmlDir is node of tree, counts are sum of descendants
+type htmlDir struct {
+	Name  string
+	N     int
+	Tags  []htmlCount
+	Dirs  []*htmlDir
+	Files []*htmlFile
+
+	tags  map[string]int
+	dirs  map[string]*htmlDir
+	files map[string]*htmlFile
+}
+
+type htmlFile struct {
+	Name    string
+	Path    string
+	Tags    []htmlCount
+	Matches []*htmlMatch
+
+	tags map[string]int
+}
+
+type htmlMatch struct {
+	Line  uint
+	Tag   string
+	Text  string
+	Blame *Blame
+	// enclosing function or type
+	Symbol string
+	// lines of context with the match
+	Lines []htmlLine
+}
+
+type htmlLine struct {
+	Num   uint
+	Text  string
+	Match bool
+}
+
+func newHTMLDir(name string) *htmlDir {
+	return &htmlDir{
+		Name:  name,
+		tags:  make(map[string]int),
+		dirs:  make(map[string]*htmlDir),
+		files: make(map[string]*htmlFile),
+	}
+}
+
+// newHTMLTree build tree of directories from results
+// results of same path are merged e.g. by Group
+func newHTMLTree(results []*gatherRes) *htmlDir {
+	root := newHTMLDir("")
+	for _, gr := range results {
+		parts := strings.Split(filepath.ToSlash(gr.path), "/")
+		dirs := []*htmlDir{root}
+		for _, name := range parts[:len(parts)-1] {
+			if name == "" && len(dirs) == 1 {
+				name = "/"
+			}
+			d := dirs[len(dirs)-1]
+			child, ok := d.dirs[name]
+			if !ok {
+				child = newHTMLDir(name)
+				d.dirs[name] = child
+				d.Dirs = append(d.Dirs, child)
+			}
+			dirs = append(dirs, child)
+		}
+		d := dirs[len(dirs)-1]
+		f, ok := d.files[gr.path]
+		if !ok {
+			f = &htmlFile{Name: parts[len(parts)-1], Path: gr.path, tags: make(map[string]int)}
+			d.files[gr.path] = f
+			d.Files = append(d.Files, f)
+		}
+		for _, m := range gr.matches {
+			for _, d := range dirs {
+				d.N++
+				d.tags[m.tag]++
+			}
+			f.tags[m.tag]++
+			f.Matches = append(f.Matches, newHTMLMatch(m))
+		}
+	}
+	root.finish()
+	return root
+}
+
+func newHTMLMatch(m *match) *htmlMatch {
+	hm := &htmlMatch{Line: m.num, Tag: m.tag, Text: m.text, Blame: m.blame, Symbol: m.symbol}
+	for i, s := range m.befores {
+		hm.Lines = append(hm.Lines, htmlLine{Num: m.num - uint(len(m.befores)-i), Text: s})
+	}
+	hm.Lines = append(hm.Lines, htmlLine{Num: m.num, Text: m.text, Match: true})
+	for i, s := range m.adds {
+		hm.Lines = append(hm.Lines, htmlLine{Num: m.num + uint(i) + 1, Text: s})
+	}
+	return hm
+}
+
+// finish sort children and fix counts
+func (d *htmlDir) finish() {
+	d.Tags = htmlCounts(d.tags)
+	sort.Slice(d.Dirs, func(i, j int) bool { return d.Dirs[i].Name < d.Dirs[j].Name })
+	sort.Slice(d.Files, func(i, j int) bool { return d.Files[i].Name < d.Files[j].Name })
+	for _, child := range d.Dirs {
+		child.finish()
+	}
+	for _, f := range d.Files {
+		f.Tags = htmlCounts(f.tags)
+		sort.SliceStable(f.Matches, func(i, j int) bool { return f.Matches[i].Line < f.Matches[j].Line })
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+details { margin-left: 1.2em; }
+summary { cursor: pointer; }
+.tag { display: inline-block; background: #eee; border-radius: 0.3em; padding: 0 0.4em; margin-left: 0.3em; font-size: 0.85em; }
+.file { font-family: monospace; }
+pre { background: #f7f7f7; padding: 0.4em; margin: 0.2em 0 0.6em 1.2em; overflow-x: auto; }
+.hit { background: #fff3b0; display: block; }
+.blame, .symbol { color: #666; font-size: 0.85em; margin-left: 1.2em; }
+</style>
+</head>
+<body>
+<h1>{{.Name}} report</h1>
+<p>{{.Matches}} matches in {{.Files}} files, generated at {{.Generated}} by {{.Name}} {{.Version}}</p>
+<h2>Tags</h2>
+<table>
+<tr><th>tag</th><th>matches</th></tr>
+{{- range .Tags}}
+<tr><td>{{.Name}}</td><td>{{.N}}</td></tr>
+{{- end}}
+</table>
+{{- if .Authors}}
+<h2>Authors</h2>
+<table>
+<tr><th>author</th><th>matches</th></tr>
+{{- range .Authors}}
+<tr><td>{{.Name}}</td><td>{{.N}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2>Files</h2>
+{{template "dir" .Root}}
+</body>
+</html>
+{{define "tags"}}{{range .}}<span class="tag">{{.Name}} {{.N}}</span>{{end}}{{end}}
+{{- define "dir"}}
+{{- range .Dirs}}
+<details open>
+<summary>{{.Name}}/ ({{.N}}){{template "tags" .Tags}}</summary>
+{{- template "dir" .}}
+</details>
+{{- end}}
+{{- range .Files}}
+<details>
+<summary class="file" title="{{.Path}}">{{.Name}} ({{len .Matches}}){{template "tags" .Tags}}</summary>
+{{- range .Matches}}
+{{- if .Symbol}}
+<div class="symbol">L{{.Line}} {{.Symbol}}</div>
+{{- end}}
+{{- if .Blame}}
+<div class="blame">L{{.Line}} {{.Blame.Author}} {{date .Blame.Date}} {{.Blame.Commit}}</div>
+{{- end}}
+<pre>{{range .Lines}}<span{{if .Match}} class="hit"{{end}}>{{printf "%5d" .Num}}: {{.Text}}</span>
+{{end}}</pre>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+`))
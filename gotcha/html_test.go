@@ -0,0 +1,55 @@
+package gotcha
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_newHTMLTree(t *testing.T) {
+	blame := &Blame{Author: "alice", Commit: "0123456789", Date: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	results := []*gatherRes{
+		{path: "src/a/x.go", matches: []*match{{num: 2, tag: "TODO: ", text: "TODO: <b>", blame: blame}}},
+		{path: "src/b.go", matches: []*match{{num: 1, tag: "FIXME: ", text: "FIXME: b"}}},
+		// same path by Group
+		{path: "src/a/x.go", matches: []*match{{num: 1, tag: "FIXME: ", text: "FIXME: x"}}},
+	}
+	root := newHTMLTree(results)
+	if root.N != 3 || len(root.Dirs) != 1 {
+		t.Fatalf("unexpected root: %#v", root)
+	}
+	src := root.Dirs[0]
+	if src.Name != "src" || src.N != 3 || len(src.Dirs) != 1 || len(src.Files) != 1 {
+		t.Fatalf("unexpected src: %#v", src)
+	}
+	exp := []htmlCount{{Name: "FIXME: ", N: 2}, {Name: "TODO: ", N: 1}}
+	if len(src.Tags) != 2 || src.Tags[0] != exp[0] || src.Tags[1] != exp[1] {
+		t.Errorf("exp=%v out=%v", exp, src.Tags)
+	}
+	a := src.Dirs[0]
+	if a.Name != "a" || a.N != 2 || len(a.Files) != 1 {
+		t.Fatalf("unexpected a: %#v", a)
+	}
+	if f := a.Files[0]; f.Name != "x.go" || len(f.Matches) != 2 || f.Matches[0].Line != 1 {
+		t.Errorf("unexpected file: %#v", f)
+	}
+
+	buf := new(bytes.Buffer)
+	hw := &htmlWriter{w: buf}
+	for _, gr := range results {
+		hw.write(gr)
+	}
+	if err := hw.flush(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"<td>alice</td><td>1</td>", "TODO: &lt;b&gt;", "src/ (3)", "a/ (2)", "2020-01-02", "3 matches in 2 files"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in %s", s, out)
+		}
+	}
+	if strings.Contains(out, "<b>") {
+		t.Errorf("expected escaped text: %s", out)
+	}
+}
@@ -0,0 +1,230 @@
+package gotcha
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFiles are read on each directories, latter has priority
+var IgnoreFiles = []string{".gitignore", ".ignore"}
+
+// ignorePattern is a line of gitignore
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// parseIgnorePattern return nil if line is blank or comment
+func parseIgnorePattern(line string) *ignorePattern {
+	// trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	p := &ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+	// separator at beginning or middle make relative to the directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/") && (i == 0 || line[i-1] == '/'):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case line[i:] == "**" && i != 0 && line[i-1] == '/':
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '\\' && i+1 < len(line):
+			i++
+			expr.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end == -1 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil
+	}
+	p.re = re
+	return p
+}
+
+func (p *ignorePattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return p.re.MatchString(rel)
+}
+
+// readIgnoreFiles read IgnoreFiles in dir
+func readIgnoreFiles(dir string) ([]*ignorePattern, error) {
+	var patterns []*ignorePattern
+	for _, base := range IgnoreFiles {
+		f, err := os.Open(filepath.Join(dir, base))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if p := parseIgnorePattern(sc.Text()); p != nil {
+				patterns = append(patterns, p)
+			}
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+// ignorer is stack of ignore rules per directories
+// paths are matched with relative path from the walk root
+type ignorer struct {
+	parent   *ignorer
+	patterns []*ignorePattern
+
+	root string // walk root
+	// base is relative path of the directory from root, "" is root
+	base string
+	// prefix is relative path of root from the directory, for above root
+	prefix string
+}
+
+// newIgnorer read ignore files of parents of root in the git work tree
+// ignore files of root are read by child(root)
+func newIgnorer(root string) (*ignorer, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// parents until the top of work tree
+	var parents []string
+	for dir, prefix := abs, ""; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// not in work tree
+			parents = nil
+			break
+		}
+		prefix = path.Join(filepath.Base(dir), prefix)
+		dir = parent
+		parents = append(parents, dir, prefix)
+	}
+
+	ig := &ignorer{root: root}
+	for i := len(parents) - 2; i >= 0; i -= 2 {
+		patterns, err := readIgnoreFiles(parents[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(patterns) != 0 {
+			ig = &ignorer{parent: ig, patterns: patterns, root: root, prefix: parents[i+1]}
+		}
+	}
+	return ig, nil
+}
+
+// child return ignorer for dir, dir is a path in the walk
+func (ig *ignorer) child(dir string) (*ignorer, error) {
+	patterns, err := readIgnoreFiles(dir)
+	if err != nil {
+		return ig, err
+	}
+	if len(patterns) == 0 {
+		return ig, nil
+	}
+	rel, err := filepath.Rel(ig.root, dir)
+	if err != nil {
+		return ig, err
+	}
+	if rel == "." {
+		rel = ""
+	}
+	return &ignorer{parent: ig, patterns: patterns, root: ig.root, base: filepath.ToSlash(rel)}, nil
+}
+
+// ignored reports whether path is ignored, path is a path in the walk
+// last matched pattern is used, deeper directories have priority
+func (ig *ignorer) ignored(p string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	rel, err := filepath.Rel(ig.root, p)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	var chain []*ignorer
+	for i := ig; i != nil; i = i.parent {
+		chain = append(chain, i)
+	}
+	for i := 0; i < len(chain); i++ {
+		level := chain[i]
+		var r string
+		switch {
+		case level.prefix != "":
+			r = path.Join(level.prefix, rel)
+		case level.base == "":
+			r = rel
+		case strings.HasPrefix(rel, level.base+"/"):
+			r = rel[len(level.base)+1:]
+		default:
+			continue
+		}
+		for j := len(level.patterns) - 1; j >= 0; j-- {
+			if level.patterns[j].match(r, isDir) {
+				return !level.patterns[j].negate
+			}
+		}
+	}
+	return false
+}
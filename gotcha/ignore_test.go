@@ -0,0 +1,119 @@
+package gotcha
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{pattern: "*.log", path: "a.log", match: true},
+		{pattern: "*.log", path: "dir/a.log", match: true},
+		{pattern: "*.log", path: "a.logs", match: false},
+		{pattern: "/a.log", path: "dir/a.log", match: false},
+		{pattern: "/a.log", path: "a.log", match: true},
+		{pattern: "build/", path: "build", isDir: true, match: true},
+		{pattern: "build/", path: "build", isDir: false, match: false},
+		{pattern: "doc/*.txt", path: "doc/a.txt", match: true},
+		{pattern: "doc/*.txt", path: "doc/sub/a.txt", match: false},
+		{pattern: "**/vendor", path: "a/b/vendor", isDir: true, match: true},
+		{pattern: "**/vendor", path: "vendor", isDir: true, match: true},
+		{pattern: "a/**/b", path: "a/b", match: true},
+		{pattern: "a/**/b", path: "a/x/y/b", match: true},
+		{pattern: "a/**", path: "a/x/y", match: true},
+		{pattern: "file?.go", path: "file1.go", match: true},
+		{pattern: "file[0-9].go", path: "file1.go", match: true},
+		{pattern: "file[!0-9].go", path: "file1.go", match: false},
+		{pattern: "\\#hash", path: "#hash", match: true},
+		{pattern: "trailing   ", path: "trailing", match: true},
+	}
+	for _, test := range tests {
+		p := parseIgnorePattern(test.pattern)
+		if p == nil {
+			t.Fatalf("pattern=%q unexpected nil", test.pattern)
+		}
+		if b := p.match(test.path, test.isDir); b != test.match {
+			t.Errorf("pattern=%q path=%q exp=%v out=%v re=%v", test.pattern, test.path, test.match, b, p.re)
+		}
+	}
+
+	for _, s := range []string{"", "# comment", "   "} {
+		if p := parseIgnorePattern(s); p != nil {
+			t.Errorf("pattern=%q expected nil but %#v", s, p)
+		}
+	}
+	if p := parseIgnorePattern("!keep.log"); p == nil || !p.negate {
+		t.Errorf("expected negate pattern but %#v", p)
+	}
+}
+
+func TestGitIgnore(t *testing.T) {
+	root := filepath.Join(TestRoot, "git_ignore")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		".gitignore":          "*.gen\nbuild/\n",
+		"keep.txt":            "TODO: keep",
+		"skip.gen":            "TODO: skip",
+		"build/out.txt":       "TODO: skip",
+		"sub/.ignore":         "!keep.gen\nlocal.txt\n",
+		"sub/keep.gen":        "TODO: keep",
+		"sub/local.txt":       "TODO: skip",
+		"sub/deep/local.txt":  "TODO: skip",
+		"sub/deep/deeper.txt": "TODO: keep",
+	}
+	for path, contents := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := []string{
+		filepath.Join(root, "keep.txt"),
+		filepath.Join(root, "sub", "deep", "deeper.txt"),
+		filepath.Join(root, "sub", "keep.gen"),
+	}
+
+	verify := func(t *testing.T, work func(g *Gotcha) *Result) {
+		g := NewGotcha()
+		errbuf := bytes.NewBufferString("")
+		g.Log.SetOutput(errbuf)
+		buf := bytes.NewBufferString("")
+		g.W = buf
+		g.Sort = "path"
+		if res := work(g); len(res.Errors) != 0 {
+			t.Fatal(res.Errors)
+		}
+		var out []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, root) {
+				out = append(out, line)
+			}
+		}
+		if strings.Join(exp, "\n") != strings.Join(out, "\n") {
+			t.Errorf("exp=%#v out=%#v", exp, out)
+		}
+	}
+	t.Run("async", func(t *testing.T) {
+		verify(t, func(g *Gotcha) *Result { return g.WorkGo(context.Background(), root, 0) })
+	})
+	t.Run("sync", func(t *testing.T) {
+		verify(t, func(g *Gotcha) *Result { return g.SyncWorkGo(context.Background(), root) })
+	})
+}
@@ -0,0 +1,209 @@
+package gotcha
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// issue actions
+const (
+	IssueOpen  = "open"
+	IssueClose = "close"
+)
+
+// Issue is exported payload of a match
+type Issue struct {
+	Action      string `json:"action"`
+	ID          string `json:"id,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Title       string `json:"title"`
+	Body        string `json:"body,omitempty"`
+	Path        string `json:"path"`
+	Line        uint   `json:"line,omitempty"`
+	Tag         string `json:"tag"`
+	Text        string `json:"text"`
+}
+
+// Fingerprint return stable id of a match from path, tag, normalized text
+// n is number of same matches before in the file, for distinguish duplicates
+func Fingerprint(path, tag, text string, n int) string {
+	h := sha256.New()
+	for _, s := range []string{path, tag, text, strconv.Itoa(n)} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// issues make issues of open from matches, paths are relative from root
+func (g *Gotcha) issues(root string, matches []*Match) []*Issue {
+	var (
+		issues []*Issue
+		seen   = make(map[baselineKey]int)
+	)
+	for _, m := range matches {
+		path := baselinePath(root, m.Path)
+		text := g.normalize(m)
+		key := baselineKey{path: path, tag: m.Tag, text: text}
+		issues = append(issues, &Issue{
+			Action:      IssueOpen,
+			Fingerprint: Fingerprint(path, m.Tag, text, seen[key]),
+			Title:       issueTitle(m.Tag, text),
+			Body:        issueBody(path, m),
+			Path:        path,
+			Line:        m.Line,
+			Tag:         m.Tag,
+			Text:        text,
+		})
+		seen[key]++
+	}
+	return issues
+}
+
+func issueTitle(tag, text string) string {
+	const max = 80
+	title := strings.Trim(tag, " :") + ": " + text
+	if r := []rune(title); len(r) > max {
+		title = string(r[:max-3]) + "..."
+	}
+	return title
+}
+
+func issueBody(path string, m *Match) string {
+	body := fmt.Sprintf("%s:%d\n\n```\n", path, m.Line)
+	for _, s := range m.Before {
+		body += s + "\n"
+	}
+	body += m.Text + "\n"
+	for _, s := range m.Context {
+		body += s + "\n"
+	}
+	body += "```\n"
+	if m.Blame != nil {
+		body += fmt.Sprintf("\n%s <%s> %s\n", m.Blame.Author, m.Blame.Email, m.Blame.Date.Format("2006-01-02"))
+	}
+	return body
+}
+
+// Tracker records opened issues by fingerprint
+type Tracker interface {
+	// Issues return opened issues by fingerprint
+	Issues() map[string]*Issue
+	// Open record the issue and assign ID
+	Open(issue *Issue) error
+	// Close remove the issue
+	Close(issue *Issue) error
+	// Save persist records
+	Save() error
+}
+
+// FileTracker is local Tracker of JSON file, IDs are sequential numbers
+type FileTracker struct {
+	path   string
+	NextID int               `json:"next_id"`
+	Items  map[string]*Issue `json:"issues"`
+}
+
+// OpenFileTracker read FileTracker from path, return empty if not exists
+func OpenFileTracker(path string) (*FileTracker, error) {
+	tr := &FileTracker{path: path, NextID: 1, Items: make(map[string]*Issue)}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tr, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, tr); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if tr.Items == nil {
+		tr.Items = make(map[string]*Issue)
+	}
+	return tr, nil
+}
+
+// Issues implement Tracker
+func (tr *FileTracker) Issues() map[string]*Issue {
+	return tr.Items
+}
+
+// Open implement Tracker
+func (tr *FileTracker) Open(issue *Issue) error {
+	issue.ID = strconv.Itoa(tr.NextID)
+	tr.NextID++
+	tr.Items[issue.Fingerprint] = issue
+	return nil
+}
+
+// Close implement Tracker
+func (tr *FileTracker) Close(issue *Issue) error {
+	delete(tr.Items, issue.Fingerprint)
+	return nil
+}
+
+// Save implement Tracker, the file is not gathered by later walks
+func (tr *FileTracker) Save() error {
+	b, err := marshalRecord(tr)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(tr.path, append(b, '\n'), 0666)
+}
+
+// ExportIssues write JSON lines of issues of new and resolved
+// if tr is nil then all matches are exported as new
+func (g *Gotcha) ExportIssues(w io.Writer, root string, matches []*Match, tr Tracker) error {
+	enc := json.NewEncoder(w)
+	current := g.issues(root, matches)
+	if tr == nil {
+		for _, issue := range current {
+			if err := enc.Encode(issue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tracked := tr.Issues()
+	exists := make(map[string]bool)
+	for _, issue := range current {
+		exists[issue.Fingerprint] = true
+		if _, ok := tracked[issue.Fingerprint]; ok {
+			continue
+		}
+		if err := tr.Open(issue); err != nil {
+			return err
+		}
+		if err := enc.Encode(issue); err != nil {
+			return err
+		}
+	}
+
+	var resolved []*Issue
+	for fp, issue := range tracked {
+		if !exists[fp] {
+			resolved = append(resolved, issue)
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].Fingerprint < resolved[j].Fingerprint })
+	for _, issue := range resolved {
+		if err := tr.Close(issue); err != nil {
+			return err
+		}
+		closed := *issue
+		closed.Action = IssueClose
+		if err := enc.Encode(&closed); err != nil {
+			return err
+		}
+	}
+	return tr.Save()
+}
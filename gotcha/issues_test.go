@@ -0,0 +1,20 @@
+package gotcha
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("a.go", "TODO: ", "hello", 0)
+	if a != Fingerprint("a.go", "TODO: ", "hello", 0) {
+		t.Error("expected stable fingerprint")
+	}
+	for _, fp := range []string{
+		Fingerprint("b.go", "TODO: ", "hello", 0),
+		Fingerprint("a.go", "FIXME: ", "hello", 0),
+		Fingerprint("a.go", "TODO: ", "world", 0),
+		Fingerprint("a.go", "TODO: ", "hello", 1),
+	} {
+		if a == fp {
+			t.Errorf("unexpected same fingerprint: %s", fp)
+		}
+	}
+}
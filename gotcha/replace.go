@@ -0,0 +1,214 @@
+package gotcha
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// diffContext is number of lines of context of unified diff
+const diffContext = 3
+
+// wordRegexp return regexp of word for expand of Replace
+// $1 is word without trailing spaces and punctuation, $2 is the trailing
+// e.g. "TODO: " is "TODO" and ": "
+func wordRegexp(word string) *regexp.Regexp {
+	head, tail := splitWord(word)
+	return regexp.MustCompile("(" + regexp.QuoteMeta(head) + ")(" + regexp.QuoteMeta(tail) + ")")
+}
+
+// splitWord split word to the stem and trailing spaces and punctuation
+func splitWord(word string) (head, tail string) {
+	head = strings.TrimRightFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	return head, word[len(head):]
+}
+
+// ExpandReplace return word of replaced by template
+// template is same as regexp.Expand, $0 is word and see wordRegexp for $1 and $2
+func ExpandReplace(template, word string) string {
+	re := wordRegexp(word)
+	return string(re.ExpandString(nil, template, word, re.FindStringSubmatchIndex(word)))
+}
+
+// replace rewrite matched words of gr by Replace
+// if DryRun then write unified diff to W instead of rewrite
+func (g *Gotcha) replace(gr *gatherRes) error {
+	if len(gr.matches) == 0 {
+		return nil
+	}
+	if g.Decompress && (isCompressed(gr.path) || strings.Contains(gr.path, ArchiveSep)) {
+		return fmt.Errorf("%s: can not replace in compressed file", gr.path)
+	}
+	// same file of links and the real path is replaced once
+	info, err := os.Stat(gr.path)
+	if err != nil {
+		return err
+	}
+	id := fileIDOf(gr.path, info)
+	g.mu.Lock()
+	done := g.replaced[id]
+	if g.replaced == nil {
+		g.replaced = make(map[fileID]bool)
+	}
+	g.replaced[id] = true
+	g.mu.Unlock()
+	if done {
+		g.Log.Printf("already replaced: [%v]\n\n", gr.path)
+		return nil
+	}
+	b, err := ioutil.ReadFile(gr.path)
+	if err != nil {
+		return err
+	}
+	olds := bytes.SplitAfter(b, []byte("\n"))
+	news := make([][]byte, len(olds))
+	copy(news, olds)
+	expanded := make(map[string]string)
+	for _, m := range gr.matches {
+		if m.wordLen != 0 {
+			// already annotated after the stem e.g. "TODO(alice): "
+			continue
+		}
+		if _, ok := expanded[m.tag]; !ok {
+			expanded[m.tag] = ExpandReplace(g.Replace, m.tag)
+		}
+		i := int(m.num) - 1
+		if i >= len(olds) {
+			return fmt.Errorf("%s:%d: line is not found, file is changed", gr.path, m.num)
+		}
+		line := olds[i]
+		off := 0
+		if i == 0 && bytes.HasPrefix(line, []byte{0xef, 0xbb, 0xbf}) {
+			// BOM is dropped by decode
+			off = 3
+		}
+		for n := 1; n < m.col && off < len(line); n++ {
+			_, size := utf8.DecodeRune(line[off:])
+			off += size
+		}
+		if !bytes.HasPrefix(line[off:], []byte(m.tag)) {
+			return fmt.Errorf("%s:%d:%d: %q is not found, file is changed or not UTF-8", gr.path, m.num, m.col, m.tag)
+		}
+		news[i] = append(append(append([]byte(nil), line[:off]...), expanded[m.tag]...), line[off+len(m.tag):]...)
+	}
+	if g.DryRun {
+		return writeDiff(g.W, gr.path, olds, news)
+	}
+	if err := writeAtomic(gr.path, bytes.Join(news, nil)); err != nil {
+		return err
+	}
+	// rename give new inode, record it for the other paths
+	if info, err := os.Stat(gr.path); err == nil {
+		g.mu.Lock()
+		g.replaced[fileIDOf(gr.path, info)] = true
+		g.mu.Unlock()
+	}
+	return nil
+}
+
+// writeAtomic write b to path by temporary file and rename, mode is preserved
+// symbolic links are resolved, the target is rewritten and the link is kept
+// new file is created with mode 0644
+func writeAtomic(path string, b []byte) error {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	perm := os.FileMode(0644)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeDiff write unified diff of olds and news to w
+// lines are replaced one by one, so line numbers are same on both
+func writeDiff(w io.Writer, path string, olds, news [][]byte) error {
+	var changed []int
+	for i := range olds {
+		if !bytes.Equal(olds[i], news[i]) {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", path, path)
+	line := func(prefix string, b []byte) {
+		buf.WriteString(prefix)
+		buf.Write(b)
+		if !bytes.HasSuffix(b, []byte("\n")) {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	for k := 0; k < len(changed); {
+		// merge changes of close to one hunk
+		start, end := changed[k]-diffContext, changed[k]+diffContext+1
+		for k++; k < len(changed) && changed[k]-diffContext <= end; k++ {
+			end = changed[k] + diffContext + 1
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end > len(olds) {
+			end = len(olds)
+		}
+		// empty last of SplitAfter is not line
+		if end == len(olds) && len(olds[end-1]) == 0 {
+			end--
+		}
+		fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for i := start; i < end; {
+			if bytes.Equal(olds[i], news[i]) {
+				line(" ", olds[i])
+				i++
+				continue
+			}
+			j := i
+			for ; j < end && !bytes.Equal(olds[j], news[j]); j++ {
+				line("-", olds[j])
+			}
+			for ; i < j; i++ {
+				line("+", news[i])
+			}
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
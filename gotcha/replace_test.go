@@ -0,0 +1,143 @@
+package gotcha
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandReplace(t *testing.T) {
+	tests := []struct {
+		template, word, exp string
+	}{
+		{template: "$1(alice)$2", word: "TODO: ", exp: "TODO(alice): "},
+		{template: "${1}[#12]$2", word: "FIXME:", exp: "FIXME[#12]:"},
+		{template: "$0$0", word: "XXX", exp: "XXXXXX"},
+		{template: "$1(x)$2", word: "課題: ", exp: "課題(x): "},
+		{template: "NOTE: $$", word: "TODO: ", exp: "NOTE: $"},
+	}
+	for _, test := range tests {
+		if out := ExpandReplace(test.template, test.word); out != test.exp {
+			t.Errorf("template=%q word=%q exp=%q out=%q", test.template, test.word, test.exp, out)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	root := filepath.Join(TestRoot, "replace")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	const in = "a\nb\n// TODO: one TODO: two\nc\nd\ne\nf\ng\nh\nx 課題: TODO: three"
+	path := filepath.Join(root, "a.go")
+	if err := ioutil.WriteFile(path, []byte(in), 0640); err != nil {
+		t.Fatal(err)
+	}
+	newGotcha := func() (*Gotcha, *bytes.Buffer) {
+		g := NewGotcha()
+		g.Log.SetOutput(ioutil.Discard)
+		buf := new(bytes.Buffer)
+		g.W = buf
+		g.Replace = "$1(alice)$2"
+		return g, buf
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		g, buf := newGotcha()
+		g.DryRun = true
+		res := g.SyncWorkGo(context.Background(), root)
+		if len(res.Errors) != 0 || res.Lines != 2 {
+			t.Fatalf("unexpected result: %#v", res)
+		}
+		exp := "--- " + path + "\n+++ " + path + "\n" +
+			"@@ -1,10 +1,10 @@\n" +
+			" a\n b\n-// TODO: one TODO: two\n+// TODO(alice): one TODO: two\n c\n d\n e\n f\n g\n h\n" +
+			"-x 課題: TODO: three\n\\ No newline at end of file\n" +
+			"+x 課題: TODO(alice): three\n\\ No newline at end of file\n"
+		if buf.String() != exp {
+			t.Errorf("exp=%q out=%q", exp, buf.String())
+		}
+		if b, _ := ioutil.ReadFile(path); string(b) != in {
+			t.Errorf("rewritten by dry run: %q", b)
+		}
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		g, buf := newGotcha()
+		res := g.WorkGo(context.Background(), root, 0)
+		if len(res.Errors) != 0 || res.Lines != 2 {
+			t.Fatalf("unexpected result: %#v", res)
+		}
+		if exp := path + "\nL3:// TODO: one TODO: two\nL10:x 課題: TODO: three\n\n"; buf.String() != exp {
+			t.Errorf("expected records of before rewrite: exp=%q out=%q", exp, buf.String())
+		}
+		exp := "a\nb\n// TODO(alice): one TODO: two\nc\nd\ne\nf\ng\nh\nx 課題: TODO(alice): three"
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != exp {
+			t.Errorf("exp=%q out=%q", exp, b)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("mode is not preserved: %v", info.Mode())
+		}
+		files, err := ioutil.ReadDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Errorf("temporary files are left: %v", files)
+		}
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		root := filepath.Join(TestRoot, "replace_symlink")
+		if err := os.MkdirAll(filepath.Join(root, "a"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(root)
+		target := filepath.Join(root, "a", "x.txt")
+		if err := ioutil.WriteFile(target, []byte("TODO: x"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(root, "f.txt")
+		if err := os.Symlink(filepath.Join("a", "x.txt"), link); err != nil {
+			t.Skip(err)
+		}
+		for _, nworker := range []uint{0, 4} {
+			if err := ioutil.WriteFile(target, []byte("TODO: x"), 0666); err != nil {
+				t.Fatal(err)
+			}
+			g, _ := newGotcha()
+			g.Follow = true
+			res := g.WorkGo(context.Background(), root, nworker)
+			if len(res.Errors) != 0 {
+				t.Fatalf("nworker=%d: unexpected errors: %v", nworker, res.Errors)
+			}
+			if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+				t.Errorf("nworker=%d: link is not kept: %v %v", nworker, info, err)
+			}
+			if b, _ := ioutil.ReadFile(target); string(b) != "TODO(alice): x" {
+				t.Errorf("nworker=%d: target is not rewritten: %q", nworker, b)
+			}
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		g, _ := newGotcha()
+		gr := &gatherRes{path: path, matches: []*match{{num: 1, col: 1, tag: "TODO: "}}}
+		if err := g.replace(gr); err == nil {
+			t.Error("expected error of mismatch but nil")
+		}
+	})
+}
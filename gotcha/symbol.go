@@ -0,0 +1,157 @@
+package gotcha
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SymbolFinder return enclosing symbols of lines in src, e.g. "func (*Gotcha) gather"
+// lines are line numbers of matches of ascending order, result is same length and "" is none
+type SymbolFinder func(src []byte, lines []uint) []string
+
+// SymbolFinders map of extension or basename to SymbolFinder
+// Go is parsed by go/parser, other languages can be added e.g. by IndentSymbols
+var SymbolFinders = map[string]SymbolFinder{
+	".go": goSymbols,
+
+	".py":   IndentSymbols(regexp.MustCompile(`^\s*(?:async\s+)?(?:def|class)\s+\w+`)),
+	".rb":   IndentSymbols(regexp.MustCompile(`^\s*(?:def|class|module)\s+[\w.:]+[?!=]?`)),
+	".js":   IndentSymbols(jsSymbol),
+	".jsx":  IndentSymbols(jsSymbol),
+	".ts":   IndentSymbols(jsSymbol),
+	".tsx":  IndentSymbols(jsSymbol),
+	".sh":   IndentSymbols(shSymbol),
+	".bash": IndentSymbols(shSymbol),
+}
+
+var (
+	jsSymbol = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?(?:function\*?|class)\s+[\w$]+`)
+	shSymbol = regexp.MustCompile(`^\s*(?:function\s+)?[\w-]+\s*\(\)`)
+)
+
+// symbolFinderFor return SymbolFinder for the path, nil if unknown language
+func symbolFinderFor(path string) SymbolFinder {
+	base := filepath.Base(path)
+	if find, ok := SymbolFinders[filepath.Ext(base)]; ok {
+		return find
+	}
+	return SymbolFinders[base]
+}
+
+// IndentSymbols return heuristic SymbolFinder of declarations matched by re
+// the symbol is the nearest preceding declaration of less indented than the line
+// text of the match of re is used as the symbol
+func IndentSymbols(re *regexp.Regexp) SymbolFinder {
+	return func(src []byte, lines []uint) []string {
+		type decl struct {
+			indent int
+			text   string
+		}
+		var (
+			res = make([]string, len(lines))
+			// declarations of enclosing the current line, outer first
+			stack []decl
+			i     int
+		)
+		for num, line := range strings.Split(string(src), "\n") {
+			if i == len(lines) {
+				break
+			}
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			blank := strings.TrimSpace(line) == ""
+			if !blank {
+				for len(stack) != 0 && stack[len(stack)-1].indent >= indent {
+					stack = stack[:len(stack)-1]
+				}
+			}
+			for ; i < len(lines) && lines[i] == uint(num+1); i++ {
+				if len(stack) != 0 {
+					res[i] = stack[len(stack)-1].text
+				}
+			}
+			if s := re.FindString(line); s != "" {
+				stack = append(stack, decl{indent: indent, text: strings.TrimSpace(s)})
+			}
+		}
+		return res
+	}
+}
+
+// goSymbols use go/parser, syntax errors are ignored as far as parsed
+// symbols are "func name", "func (*Recv) name" and "type name", doc comments are included
+func goSymbols(src []byte, lines []uint) []string {
+	type scope struct {
+		start, end uint
+		name       string
+	}
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	var scopes []scope
+	add := func(doc *ast.CommentGroup, node ast.Node, name string) {
+		pos := node.Pos()
+		if doc != nil {
+			pos = doc.Pos()
+		}
+		// end of incomplete declaration is not in the file
+		start, end := fset.Position(pos).Line, fset.Position(node.End()).Line
+		if start == 0 || end < start {
+			return
+		}
+		scopes = append(scopes, scope{start: uint(start), end: uint(end), name: name})
+	}
+	if file != nil {
+		for _, d := range file.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				name := "func " + d.Name.Name
+				if d.Recv != nil && len(d.Recv.List) != 0 {
+					name = "func (" + types.ExprString(d.Recv.List[0].Type) + ") " + d.Name.Name
+				}
+				add(d.Doc, d, name)
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					doc := ts.Doc
+					if doc == nil && !d.Lparen.IsValid() {
+						doc = d.Doc
+					}
+					add(doc, ts, "type "+ts.Name.Name)
+				}
+			}
+		}
+	}
+	res := make([]string, len(lines))
+	for i, line := range lines {
+		for _, sc := range scopes {
+			if sc.start <= line && line <= sc.end {
+				res[i] = sc.name
+				break
+			}
+		}
+	}
+	return res
+}
+
+// attachSymbols set symbols of gr.matches by find
+func attachSymbols(gr *gatherRes, src []byte, find SymbolFinder) {
+	if len(gr.matches) == 0 {
+		return
+	}
+	lines := make([]uint, len(gr.matches))
+	for i, m := range gr.matches {
+		lines[i] = m.num
+	}
+	for i, s := range find(src, lines) {
+		if i < len(gr.matches) {
+			gr.matches[i].symbol = s
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package gotcha
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_goSymbols(t *testing.T) {
+	src := `package main
+
+// TODO: top
+
+// Gotcha is
+// TODO: doc
+type Gotcha struct {
+	W int // TODO: field
+}
+
+type (
+	a int
+	// TODO: grouped
+	b int
+)
+
+func (g *Gotcha) gather() {
+	// TODO: method
+}
+
+func main() {
+	f := func() {
+		// TODO: literal
+	}
+	f()
+}
+`
+	lines := []uint{3, 6, 8, 13, 17, 18, 23}
+	exp := []string{"", "type Gotcha", "type Gotcha", "type b", "func (*Gotcha) gather", "func (*Gotcha) gather", "func main"}
+	if out := goSymbols([]byte(src), lines); !reflect.DeepEqual(exp, out) {
+		t.Errorf("exp=%q out=%q", exp, out)
+	}
+
+	// syntax error after the func
+	src = "package main\n\nfunc a() {\n\t// TODO: a\n}\n\nfunc b( {\n"
+	if out := goSymbols([]byte(src), []uint{4}); !reflect.DeepEqual([]string{"func a"}, out) {
+		t.Errorf("unexpected symbols: %q", out)
+	}
+}
+
+func TestIndentSymbols(t *testing.T) {
+	src := strings.Join([]string{
+		"# TODO: top",
+		"class A:",
+		"    def f(self):",
+		"        # TODO: f",
+		"",
+		"        pass",
+		"    # TODO: A",
+		"async def g():",
+		"    pass",
+		"# TODO: top",
+	}, "\n")
+	find := SymbolFinders[".py"]
+	exp := []string{"", "def f", "class A", ""}
+	if out := find([]byte(src), []uint{1, 4, 7, 10}); !reflect.DeepEqual(exp, out) {
+		t.Errorf("exp=%q out=%q", exp, out)
+	}
+}
+
+func TestGatherSymbols(t *testing.T) {
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.Symbols = true
+	src := "package main\n\nfunc main() {\n\t// TODO: hello\n}\n"
+	gr := g.gatherReader("main.go", "main.go", strings.NewReader(src))
+	if gr.err != nil {
+		t.Fatal(gr.err)
+	}
+	buf := new(bytes.Buffer)
+	if err := gr.Fwrite(buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := "main.go\nL4 (func main):\t// TODO: hello\n\n"
+	if buf.String() != exp {
+		t.Errorf("exp=%q out=%q", exp, buf.String())
+	}
+
+	// unknown language
+	gr = g.gatherReader("a.txt", "a.txt", strings.NewReader("TODO: hello"))
+	if len(gr.matches) != 1 || gr.matches[0].symbol != "" {
+		t.Errorf("unexpected matches: %#v", gr.matches)
+	}
+}
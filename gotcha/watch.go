@@ -0,0 +1,341 @@
+package gotcha
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Change is difference of matches of a file by Watch
+type Change struct {
+	Path    string
+	Added   []*Match
+	Removed []*Match
+	// running totals after the change
+	Files uint
+	Lines uint
+}
+
+// watchEvent is notified change of path, see watch_linux.go
+type watchEvent struct {
+	path string
+	dir  bool
+	// removed or moved out
+	gone bool
+	// events are lost, need to rescan all
+	overflow bool
+}
+
+// watchDir is watched directory and ignore rules of the entries
+type watchDir struct {
+	dir walkDir
+	ig  *ignorer
+}
+
+// watcher keep matches of each files for diff of changes
+type watcher struct {
+	g    *Gotcha
+	in   *inotify
+	root string
+	// root is a file, only it is watched in the parent
+	file bool
+	// watched directories
+	dirs map[string]watchDir
+	// current matches by path of gatherRes
+	state map[string][]*Match
+}
+
+// Watch scan root by WorkGo, then subscribe changes of walked directories
+// and re-gather only changed files until ctx is done
+// fn is called with each change of matches, the work stop by error of fn
+func (g *Gotcha) Watch(ctx context.Context, root string, nworker uint, fn func(c *Change) error) *Result {
+	g.reset()
+	in, err := newInotify()
+	if err != nil {
+		g.fail(err)
+		return g.result()
+	}
+	defer in.close()
+	w := &watcher{
+		g:     g,
+		in:    in,
+		root:  filepath.Clean(root),
+		dirs:  make(map[string]watchDir),
+		state: make(map[string][]*Match),
+	}
+	if err := w.run(ctx, nworker, fn); err != nil && ctx.Err() == nil {
+		g.fail(err)
+	}
+	return g.result()
+}
+
+func (w *watcher) run(ctx context.Context, nworker uint, fn func(c *Change) error) error {
+	g := w.g
+	// subscribe before scan, changes while scan are not lost
+	info, err := os.Stat(w.root)
+	if err != nil {
+		return err
+	}
+	if w.file = !info.IsDir(); w.file {
+		if err := w.in.add(filepath.Dir(w.root)); err != nil {
+			return err
+		}
+	} else if err := w.addDir(ctx, g.newWalkDir(w.root, info), nil); err != nil {
+		return err
+	}
+
+	err = g.workAsync(ctx, []string{w.root}, nworker, func(gr *gatherRes) error {
+		if gr.Err() == nil {
+			w.state[gr.path] = gr.toMatches()
+		}
+		return g.emit(gr)
+	})
+	if err := g.flush(); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblock read
+			w.in.close()
+		case <-done:
+		}
+	}()
+	for {
+		evs, err := w.in.read()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		changes, err := w.handle(ctx, evs)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// addDir watch dir and directories of under it by same walk of the scan
+// if changed is not nil then target files are gathered to it
+func (w *watcher) addDir(ctx context.Context, dir walkDir, changed map[string]bool) error {
+	enter := func(dir walkDir, ig *ignorer) error {
+		if err := w.in.add(dir.path); err != nil {
+			return w.g.report(err)
+		}
+		w.dirs[dir.path] = watchDir{dir: dir, ig: ig}
+		return nil
+	}
+	return w.g.walkWith(ctx, dir, enter, func(path string) error {
+		if changed != nil {
+			changed[path] = true
+		}
+		return nil
+	})
+}
+
+// handle events and return changes of matches in order of path
+func (w *watcher) handle(ctx context.Context, evs []watchEvent) ([]*Change, error) {
+	// paths of files to re-gather, false is removed
+	changed := make(map[string]bool)
+	for _, ev := range evs {
+		switch {
+		case ev.overflow:
+			return w.rescan(ctx)
+		case w.file:
+			if ev.path == w.root {
+				changed[ev.path] = !ev.gone
+			}
+		case ev.dir && ev.gone:
+			for dir := range w.dirs {
+				if dir == ev.path || strings.HasPrefix(dir, ev.path+string(filepath.Separator)) {
+					delete(w.dirs, dir)
+				}
+			}
+			for path := range w.state {
+				if strings.HasPrefix(path, ev.path+string(filepath.Separator)) {
+					changed[path] = false
+				}
+			}
+		case ev.dir:
+			parent, ok := w.dirs[filepath.Dir(ev.path)]
+			if !ok {
+				continue
+			}
+			info, err := os.Lstat(ev.path)
+			if err != nil {
+				continue
+			}
+			child, isDir, _ := w.g.entry(parent.dir, parent.ig, ev.path, info)
+			if !isDir {
+				continue
+			}
+			if err := w.addDir(ctx, child, changed); err != nil {
+				return nil, err
+			}
+		default:
+			changed[ev.path] = !ev.gone
+		}
+	}
+
+	paths := make([]string, 0, len(changed))
+	for path := range changed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var changes []*Change
+	for _, path := range paths {
+		var grs []*gatherRes
+		if changed[path] && w.isTarget(path) {
+			grs = w.g.gatherFile(path)
+		}
+		cs, err := w.update(path, grs)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, cs...)
+	}
+	return changes, nil
+}
+
+// isTarget reports whether path of changed file is target of the walk
+func (w *watcher) isTarget(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if w.file {
+		return path == w.root && info.Mode().IsRegular()
+	}
+	parent, ok := w.dirs[filepath.Dir(path)]
+	if !ok {
+		return false
+	}
+	_, _, isFile := w.g.entry(parent.dir, parent.ig, path, info)
+	return isFile
+}
+
+// update state of path by grs, nil grs is removed
+// members of archive of path are replaced together
+func (w *watcher) update(path string, grs []*gatherRes) ([]*Change, error) {
+	next := make(map[string][]*Match)
+	for _, gr := range grs {
+		if err := gr.Err(); err != nil {
+			if err := w.g.report(err); err != nil {
+				return nil, err
+			}
+			// keep previous
+			return nil, nil
+		}
+		next[gr.path] = gr.toMatches()
+	}
+	return w.apply(next, func(key string) bool {
+		return key == path || strings.HasPrefix(key, path+ArchiveSep)
+	}), nil
+}
+
+// apply next to state of paths of next and paths of in the scope
+func (w *watcher) apply(next map[string][]*Match, scope func(path string) bool) []*Change {
+	var paths []string
+	for path := range w.state {
+		if _, ok := next[path]; !ok && scope(path) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range next {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var changes []*Change
+	for _, path := range paths {
+		if c := w.set(path, next[path]); c != nil {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// set matches of path and return the change, nil if not changed
+func (w *watcher) set(path string, ms []*Match) *Change {
+	old := w.state[path]
+	c := &Change{Path: path}
+	// matches are compared by tag and text, line numbers may be moved
+	key := func(m *Match) string { return m.Tag + "\x00" + m.Text }
+	diff := func(ms, base []*Match) []*Match {
+		n := make(map[string]int)
+		for _, m := range base {
+			n[key(m)]++
+		}
+		var res []*Match
+		for _, m := range ms {
+			if k := key(m); n[k] > 0 {
+				n[k]--
+			} else {
+				res = append(res, m)
+			}
+		}
+		return res
+	}
+	c.Added, c.Removed = diff(ms, old), diff(old, ms)
+	if len(ms) == 0 {
+		delete(w.state, path)
+	} else {
+		w.state[path] = ms
+	}
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		return nil
+	}
+	w.g.recount(old, ms)
+	res := w.g.result()
+	c.Files, c.Lines = res.Files, res.Lines
+	return c
+}
+
+// rescan all of root for lost events
+func (w *watcher) rescan(ctx context.Context) ([]*Change, error) {
+	w.g.Log.Printf("events are overflowed, rescan: [%v]\n\n", w.root)
+	next := make(map[string][]*Match)
+	err := w.g.workAsync(ctx, []string{w.root}, 0, func(gr *gatherRes) error {
+		if err := gr.Err(); err != nil {
+			return w.g.report(err)
+		}
+		next[gr.path] = gr.toMatches()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w.apply(next, func(string) bool { return true }), nil
+}
+
+// recount replace counts of old matches of a file by ms
+func (g *Gotcha) recount(old, ms []*Match) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(old) != 0 {
+		g.nfiles--
+	}
+	if len(ms) != 0 {
+		g.nfiles++
+	}
+	g.nlines = g.nlines - uint(len(old)) + uint(len(ms))
+	for _, m := range old {
+		g.ntags[m.Tag]--
+	}
+	for _, m := range ms {
+		g.ntags[m.Tag]++
+	}
+}
@@ -0,0 +1,92 @@
+//go:build linux
+// +build linux
+
+package gotcha
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// events of watched directories
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+// inotify is watcher of directories by inotify(7)
+type inotify struct {
+	fd int
+	// non blocking fd, Read is canceled by Close
+	f *os.File
+
+	mu   sync.Mutex
+	dirs map[int32]string
+}
+
+func newInotify() (*inotify, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	return &inotify{
+		fd:   fd,
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		dirs: make(map[int32]string),
+	}, nil
+}
+
+// add watch of dir
+func (in *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(in.fd, dir, watchMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.dirs[int32(wd)] = dir
+	return nil
+}
+
+// read wait and return events
+func (in *inotify) read() ([]watchEvent, error) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	n, err := in.f.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	var evs []watchEvent
+	for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		name := bytes.TrimRight(buf[off+syscall.SizeofInotifyEvent:off+syscall.SizeofInotifyEvent+int(raw.Len)], "\x00")
+		off += syscall.SizeofInotifyEvent + int(raw.Len)
+
+		switch {
+		case raw.Mask&syscall.IN_Q_OVERFLOW != 0:
+			evs = append(evs, watchEvent{overflow: true})
+			continue
+		case raw.Mask&syscall.IN_IGNORED != 0:
+			// the directory is removed
+			delete(in.dirs, raw.Wd)
+			continue
+		}
+		dir, ok := in.dirs[raw.Wd]
+		if !ok || len(name) == 0 {
+			continue
+		}
+		evs = append(evs, watchEvent{
+			path: filepath.Join(dir, string(name)),
+			dir:  raw.Mask&syscall.IN_ISDIR != 0,
+			gone: raw.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0,
+		})
+	}
+	return evs, nil
+}
+
+func (in *inotify) close() error {
+	return in.f.Close()
+}
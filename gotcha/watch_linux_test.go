@@ -0,0 +1,177 @@
+//go:build linux
+// +build linux
+
+package gotcha
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// notifyWriter notify first write
+type notifyWriter chan struct{}
+
+func (nw notifyWriter) Write(p []byte) (int, error) {
+	select {
+	case nw <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func TestWatch(t *testing.T) {
+	root := filepath.Join(TestRoot, "watch")
+	if err := os.MkdirAll(root, 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	write := func(name, s string) {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "TODO: a\n")
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	scanned := make(notifyWriter, 1)
+	g.W = scanned
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan *Change, 16)
+	done := make(chan *Result)
+	go func() {
+		done <- g.Watch(ctx, root, 0, func(c *Change) error {
+			changes <- c
+			return nil
+		})
+	}()
+	select {
+	case <-scanned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("initial scan is timed out")
+	}
+
+	next := func() *Change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("change is timed out")
+		}
+		return nil
+	}
+	verify := func(c *Change, path string, added, removed []string, files, lines uint) {
+		t.Helper()
+		texts := func(ms []*Match) []string {
+			var res []string
+			for _, m := range ms {
+				res = append(res, m.Text)
+			}
+			return res
+		}
+		if c.Path != filepath.Join(root, path) || !equalStrings(texts(c.Added), added) || !equalStrings(texts(c.Removed), removed) ||
+			c.Files != files || c.Lines != lines {
+			t.Errorf("unexpected change: path=%s added=%q removed=%q files=%d lines=%d",
+				c.Path, texts(c.Added), texts(c.Removed), c.Files, c.Lines)
+		}
+	}
+
+	write("b.txt", "TODO: b\n")
+	verify(next(), "b.txt", []string{"TODO: b"}, nil, 2, 2)
+
+	write("a.txt", "none\nTODO: a\nTODO: a2\n")
+	verify(next(), "a.txt", []string{"TODO: a2"}, nil, 2, 3)
+
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	write(filepath.Join("sub", "c.txt"), "TODO: c\n")
+	verify(next(), filepath.Join("sub", "c.txt"), []string{"TODO: c"}, nil, 3, 4)
+
+	if err := os.Remove(filepath.Join(root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	verify(next(), "b.txt", nil, []string{"TODO: b"}, 2, 3)
+
+	if err := os.RemoveAll(filepath.Join(root, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	verify(next(), filepath.Join("sub", "c.txt"), nil, []string{"TODO: c"}, 1, 2)
+
+	cancel()
+	select {
+	case res := <-done:
+		if len(res.Errors) != 0 {
+			t.Errorf("unexpected errors: %v", res.Errors)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch is not stopped by cancel")
+	}
+}
+
+func TestWatchMaxDepth(t *testing.T) {
+	root := filepath.Join(TestRoot, "watch_max_depth")
+	if err := os.MkdirAll(filepath.Join(root, "sub", "deep"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	write := func(name, s string) {
+		if err := ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "TODO: a\n")
+
+	g := NewGotcha()
+	g.Log.SetOutput(ioutil.Discard)
+	g.MaxDepth = 2
+	scanned := make(notifyWriter, 1)
+	g.W = scanned
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *Change, 16)
+	go g.Watch(ctx, root, 0, func(c *Change) error {
+		changes <- c
+		return nil
+	})
+	select {
+	case <-scanned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("initial scan is timed out")
+	}
+
+	// out of the depth, same as the walk
+	write("sub/deep/x.txt", "TODO: x\n")
+	if err := os.Mkdir(filepath.Join(root, "sub", "new"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	write("sub/new/y.txt", "TODO: y\n")
+	write("sub/z.txt", "TODO: z\n")
+	for {
+		select {
+		case c := <-changes:
+			if c.Path == filepath.Join(root, "sub", "z.txt") {
+				return
+			}
+			t.Fatalf("unexpected change of out of max depth: %s", c.Path)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change is timed out")
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package gotcha
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// inotify is not available
+type inotify struct{}
+
+func newInotify() (*inotify, error) {
+	return nil, fmt.Errorf("watch is not supported on %s", runtime.GOOS)
+}
+
+func (in *inotify) add(dir string) error        { return nil }
+func (in *inotify) read() ([]watchEvent, error) { return nil, nil }
+func (in *inotify) close() error                { return nil }